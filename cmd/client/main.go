@@ -14,7 +14,9 @@ import (
 	"github.com/aube/keeper/internal/client/utils/helpers"
 	"github.com/aube/keeper/internal/client/utils/httpclient"
 	"github.com/aube/keeper/internal/client/utils/logger"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/aube/keeper/internal/ui"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -26,9 +28,10 @@ var (
 func main() {
 	ctx := context.Background()
 
-	fmt.Printf("Build version: %s\n", helpers.StringOrNA(buildVersion))
-	fmt.Printf("Build date: %s\n", helpers.StringOrNA(buildTime))
-	fmt.Printf("Build commit: %s\n\n", helpers.StringOrNA(buildCommit))
+	// Сведения о сборке выводятся в stderr, чтобы не смешиваться с отчётами
+	fmt.Fprintf(os.Stderr, "Build version: %s\n", helpers.StringOrNA(buildVersion))
+	fmt.Fprintf(os.Stderr, "Build date: %s\n", helpers.StringOrNA(buildTime))
+	fmt.Fprintf(os.Stderr, "Build commit: %s\n\n", helpers.StringOrNA(buildCommit))
 
 	var command string
 	if len(os.Args) > 1 {
@@ -37,6 +40,19 @@ func main() {
 
 	// конфиг
 	cfg := config.NewConfig()
+
+	// Ключевой файл создаётся до входа и не требует имени пользователя
+	if command == "keyfile" {
+		if pflag.Arg(1) != "generate" {
+			log.Fatalf("Usage: keyfile generate --keyfile <path>")
+		}
+		if err := masterkey.GenerateKeyFile(cfg.KeyFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, "Ключевой файл создан:", cfg.KeyFile)
+		return
+	}
+
 	if cfg.Username == "" {
 		log.Fatalf("Username must be set: -u <username>")
 	}
@@ -44,9 +60,31 @@ func main() {
 	// логгер
 	logger.Init(cfg.LogLevel)
 
+	// В хранилище лежат токен и зашифрованные файлы: другие пользователи
+	// не должны иметь к нему доступа
+	if err := filestore.CheckPermissions(cfg.StoragePath); err != nil {
+		if !cfg.FixPermissions {
+			log.Fatalf("Error: %v\nИсправьте права или запустите клиент с --fix_permissions", err)
+		}
+		if err := filestore.FixPermissions(cfg.StoragePath); err != nil {
+			log.Fatalf("Failed to fix storage permissions: %v", err)
+		}
+		if err := filestore.CheckPermissions(cfg.StoragePath); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, "Права хранилища исправлены:", cfg.StoragePath)
+	}
+
 	// инициализация хранилищ
 	filesStoragePath := filepath.Join(cfg.StoragePath, "files", cfg.Username)
-	filesRepo, err := filestore.NewFileSystemRepository(filesStoragePath)
+	filesRepo, err := filestore.NewFileSystemRepository(
+		filesStoragePath,
+		filestore.WithCipher(cfg.Cipher),
+		filestore.WithCompression(cfg.Compress),
+		filestore.WithChunkSize(cfg.ChunkSize),
+		filestore.WithWorkers(cfg.Workers),
+		filestore.WithOverwrite(cfg.Force),
+	)
 	if err != nil {
 		log.Fatalf("Failed to initialize file repository: %v", err)
 	}
@@ -61,6 +99,32 @@ func main() {
 		log.Fatalf("Failed to initialize tokens repository: %v", err)
 	}
 
+	// копии файлов и журнал незавершённой смены пароля
+	rekeyStoragePath := filepath.Join(cfg.StoragePath, "rekey")
+	rekeyRepo, err := filestore.NewFileSystemRepository(rekeyStoragePath)
+	if err != nil {
+		log.Fatalf("Failed to initialize rekey repository: %v", err)
+	}
+	stageRepo, err := filestore.NewFileSystemRepository(filepath.Join(rekeyStoragePath, cfg.Username))
+	if err != nil {
+		log.Fatalf("Failed to initialize rekey repository: %v", err)
+	}
+
+	// параметры Argon2id учётных записей
+	profileRepo, err := filestore.NewFileSystemRepository(filepath.Join(cfg.StoragePath, "profile"))
+	if err != nil {
+		log.Fatalf("Failed to initialize profile repository: %v", err)
+	}
+
+	// зашифрованный индекс имён записей
+	indexRepo, err := filestore.NewFileSystemRepository(
+		filepath.Join(cfg.StoragePath, "index"),
+		filestore.WithCipher(cfg.Cipher),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize index repository: %v", err)
+	}
+
 	// инициализация http-клиента
 	http := httpclient.NewHTTPClient(cfg.ServerAddress)
 
@@ -69,25 +133,70 @@ func main() {
 		filesRepo,
 		tokensRepo,
 		syncsRepo,
+		rekeyRepo,
+		stageRepo,
+		profileRepo,
+		indexRepo,
 		http,
 	)
 
+	// Доводим до конца смену пароля, прерванную при переносе файлов
+	if err := app.RecoverRekey(); err != nil {
+		log.Fatalf("Failed to recover rekey: %v", err)
+	}
+
 	// Запускаем команду из CLI или стартуем UI
 	switch command {
 	case "register":
 		err = app.Register(cfg.Username, cfg.Password, cfg.Email)
 	case "login":
-		err = app.Login(cfg.Username, cfg.Password)
+		if cfg.Legacy {
+			if err = app.LegacyLogin(cfg.Username, cfg.Password); err == nil {
+				fmt.Fprintln(os.Stderr, "Учётная запись переведена на ключ авторизации, входите командой login без --legacy")
+			}
+		} else {
+			err = app.Login(cfg.Username, cfg.Password)
+		}
 	case "encrypt":
 		err = app.Encrypt(cfg.Password, cfg.Input, cfg.Output)
 	case "card":
-		err = app.Card(cfg.Password, cfg.Number, cfg.Date, cfg.CVV)
+		err = runCard(app, cfg)
 	case "decrypt":
 		err = app.Decrypt(cfg.Password, cfg.Input, cfg.Output)
+	case "readcard":
+		err = app.Readcard(cfg.Number, cfg.Password)
+	case "cred":
+		err = runCred(app, cfg)
+	case "note":
+		err = runNote(app, cfg)
+	case "totp":
+		err = runTotp(app, cfg)
 	case "download":
-		err = app.Download(cfg.Input)
+		err = app.Download(cfg.Password, cfg.Input)
 	case "sync":
 		err = app.Sync(cfg.Username)
+	case "rekey":
+		err = app.Rekey(cfg.Password, cfg.NewPassword)
+	case "split":
+		var shares string
+		shares, err = app.Split(cfg.Password, cfg.Shares, cfg.Threshold, cfg.Output)
+		if shares != "" {
+			fmt.Println(shares)
+		}
+	case "recover":
+		err = app.Recover(cfg.Share, cfg.NewPassword)
+	case "list":
+		var entries string
+		entries, err = app.List(pflag.Arg(1), cfg.Password)
+		if entries != "" {
+			fmt.Println(entries)
+		}
+	case "verify":
+		var report string
+		report, err = app.Verify(cfg.Password, cfg.JSON)
+		if report != "" {
+			fmt.Println(report)
+		}
 	default:
 		err = ui.NewUI(app)
 	}
@@ -104,3 +213,85 @@ func main() {
 		os.Exit(1)
 	}()
 }
+
+// runCard проверяет поля карты, предупреждает об истёкшем сроке действия
+// и сохраняет карту.
+func runCard(app *client.App, cfg config.EnvConfig) error {
+	brand, expired, err := app.CheckCard(cfg.Number, cfg.Date, cfg.CVV, cfg.PIN)
+	if err != nil {
+		return err
+	}
+	if brand != "" {
+		fmt.Fprintln(os.Stderr, "Платёжная система:", brand)
+	}
+	if expired {
+		fmt.Fprintln(os.Stderr, "Внимание: срок действия карты истёк")
+	}
+	return app.Card(cfg.Number, cfg.Date, cfg.CVV, cfg.Holder, cfg.Bank, cfg.PIN, cfg.Notes, cfg.Password)
+}
+
+// runCred выполняет подкоманду cred: add, get, list или update.
+func runCred(app *client.App, cfg config.EnvConfig) error {
+	name := pflag.Arg(2)
+	switch pflag.Arg(1) {
+	case "add":
+		return app.CredAdd(name, cfg.URL, cfg.Login, cfg.Secret, cfg.Notes, cfg.Password)
+	case "get":
+		text, err := app.CredGet(name, cfg.Password)
+		if text != "" {
+			fmt.Println(text)
+		}
+		return err
+	case "list":
+		text, err := app.CredList(cfg.Password)
+		if text != "" {
+			fmt.Println(text)
+		}
+		return err
+	case "update":
+		// Заданный пустым флаг очищает поле, незаданный — не меняет
+		changed := func(flag, value string) *string {
+			if !pflag.CommandLine.Changed(flag) {
+				return nil
+			}
+			return &value
+		}
+		return app.CredUpdate(name, changed("url", cfg.URL), changed("login", cfg.Login), changed("secret", cfg.Secret), changed("notes", cfg.Notes), cfg.Password)
+	}
+	return fmt.Errorf("usage: cred add|get|list|update <name>")
+}
+
+// runNote выполняет подкоманду note: add или show.
+func runNote(app *client.App, cfg config.EnvConfig) error {
+	name := pflag.Arg(2)
+	switch pflag.Arg(1) {
+	case "add":
+		return app.NoteAdd(name, pflag.Arg(3), cfg.Password)
+	case "show":
+		text, err := app.NoteShow(name, cfg.Password)
+		if err != nil {
+			return err
+		}
+		// Текст выводится как есть, чтобы его можно было передать в pipe
+		fmt.Print(text)
+		return nil
+	}
+	return fmt.Errorf("usage: note add <name> [text|-] | note show <name>")
+}
+
+// runTotp выполняет подкоманду totp: add или code.
+func runTotp(app *client.App, cfg config.EnvConfig) error {
+	name := pflag.Arg(2)
+	switch pflag.Arg(1) {
+	case "add":
+		return app.TotpAdd(name, pflag.Arg(3), cfg.Secret, cfg.Algorithm, cfg.Digits, cfg.Period, cfg.Password)
+	case "code":
+		code, remaining, err := app.TotpCode(name, cfg.Password)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (осталось %d с)\n", code, remaining)
+		return nil
+	}
+	return fmt.Errorf("usage: totp add <name> [otpauth-uri] | totp code <name>")
+}
@@ -2,18 +2,36 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/aube/keeper/internal/client/config"
 	"github.com/aube/keeper/internal/client/entities"
 	"github.com/aube/keeper/internal/client/modules/card"
+	"github.com/aube/keeper/internal/client/modules/credential"
 	"github.com/aube/keeper/internal/client/modules/decrypt"
 	"github.com/aube/keeper/internal/client/modules/download"
 	"github.com/aube/keeper/internal/client/modules/encrypt"
+	"github.com/aube/keeper/internal/client/modules/index"
+	"github.com/aube/keeper/internal/client/modules/list"
 	"github.com/aube/keeper/internal/client/modules/login"
+	"github.com/aube/keeper/internal/client/modules/note"
+	"github.com/aube/keeper/internal/client/modules/password"
+	"github.com/aube/keeper/internal/client/modules/profile"
+	"github.com/aube/keeper/internal/client/modules/readcard"
+	"github.com/aube/keeper/internal/client/modules/recovery"
 	"github.com/aube/keeper/internal/client/modules/register"
+	"github.com/aube/keeper/internal/client/modules/rekey"
 	"github.com/aube/keeper/internal/client/modules/sync"
+	"github.com/aube/keeper/internal/client/modules/totp"
 	"github.com/aube/keeper/internal/client/modules/upload"
+	"github.com/aube/keeper/internal/client/modules/verify"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 )
 
 type FileRepository interface {
@@ -22,44 +40,92 @@ type FileRepository interface {
 	Delete(ctx context.Context, uuid string) error
 	GetFile(ctx context.Context, uuid string) (io.ReadCloser, error)
 	GetFileContent(ctx context.Context, uuid string) (string, error)
-	DecryptFile(inputName, outputPath, password string) error
-	EncryptFile(inputPath, outputName, password string) error
+	DecryptFile(inputName, outputPath string, key *masterkey.Key) (string, error)
+	DecryptStream(src io.Reader, outputPath string, key *masterkey.Key) (string, error)
+	EncryptFile(inputPath, outputName string, key *masterkey.Key, meta entities.Metadata) error
+	RekeyFile(inputName, outputPath string, oldKey, newKey *masterkey.Key) error
+	NeedsPassword(filename string) (bool, error)
+	ReplaceFile(filename, sourcePath string) error
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+	ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error)
+	VerifyFile(filename string, key *masterkey.Key) (bool, error)
+	CheckKey(filename string, key *masterkey.Key) error
 	GetPath(filename string) string
 	Exists(filename string) bool
 }
 
+type IndexRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+	Delete(ctx context.Context, filename string) error
+}
+
 type TokenRepository interface {
 	Save(ctx context.Context, filename string, data io.Reader) error
 	GetFileContent(ctx context.Context, filename string) (string, error)
 }
 
+type StagingRepository interface {
+	GetPath(filename string) string
+	Exists(filename string) bool
+	CheckKey(filename string, key *masterkey.Key) error
+}
+
+type StateRepository interface {
+	Save(ctx context.Context, filename string, data io.Reader) error
+	GetFileContent(ctx context.Context, filename string) (string, error)
+	Delete(ctx context.Context, filename string) error
+}
+
 type HTTPClient interface {
 	SetHeader(key, value string)
 	Get(endpoint string, queryParams map[string]string) ([]byte, error)
 	Post(endpoint string, body any) ([]byte, error)
 	DownloadFile(fileURL, outputPath string) error
+	OpenFile(fileURL string) (io.ReadCloser, error)
 	UploadFile(ctx context.Context, endpoint string, filePath string, formFields map[string]string) ([]byte, error)
 }
 
 type KeeperApp interface {
 	Register(Username string, Password string, Email string) error
 	Login(Username string, Password string) error
+	LegacyLogin(Username string, Password string) error
 	Encrypt(Password string, Input string, Output string) error
 	Decrypt(Password string, Input string, Output string) error
 	Upload(Output string) error
-	Download(Input string) error
+	Download(Password string, Input string) error
 	Delete(Input string) error
-	Card(Number string, Date string, CVV string, Password string) (string, error)
+	CheckCard(Number string, Date string, CVV string, PIN string) (string, bool, error)
+	Card(Number string, Date string, CVV string, Holder string, Bank string, PIN string, Notes string, Password string) error
+	Readcard(Number string, Password string) error
 	Deletecard(Input string) error
+	CredAdd(Name string, URL string, Login string, Secret string, Notes string, Password string) error
+	CredGet(Name string, Password string) (string, error)
+	CredList(Password string) (string, error)
+	CredUpdate(Name string, URL *string, Login *string, Secret *string, Notes *string, Password string) error
+	NoteAdd(Name string, Text string, Password string) error
+	NoteSave(Name string, Text string, Password string) error
+	NoteShow(Name string, Password string) (string, error)
+	TotpAdd(Name string, URI string, Secret string, Algorithm string, Digits int, Period int, Password string) error
+	TotpCode(Name string, Password string) (string, int, error)
+	TotpGenerator(Name string, Password string) (func(now time.Time) (string, time.Duration, error), error)
 	Sync(Username string) error
+	Rekey(OldPassword string, NewPassword string) error
+	List(Query string, Password string) (string, error)
+	Verify(Password string, JSON bool) (string, error)
 }
 
 type App struct {
-	filesRepo  FileRepository
-	tokensRepo TokenRepository
-	syncsRepo  TokenRepository
-	http       HTTPClient
-	cfg        config.EnvConfig
+	filesRepo   FileRepository
+	tokensRepo  TokenRepository
+	syncsRepo   TokenRepository
+	rekeyRepo   StateRepository
+	stageRepo   StagingRepository
+	profileRepo StateRepository
+	index       *index.Index
+	http        HTTPClient
+	cfg         config.EnvConfig
 }
 
 func NewApp(
@@ -67,6 +133,10 @@ func NewApp(
 	filesRepo FileRepository,
 	tokensRepo TokenRepository,
 	syncsRepo TokenRepository,
+	rekeyRepo StateRepository,
+	stageRepo StagingRepository,
+	profileRepo StateRepository,
+	indexRepo IndexRepository,
 	http HTTPClient,
 ) *App {
 	ctx := context.Background()
@@ -74,50 +144,532 @@ func NewApp(
 		http.SetHeader("Authorization", "Bearer "+token)
 	}
 	return &App{
-		filesRepo:  filesRepo,
-		tokensRepo: tokensRepo,
-		syncsRepo:  syncsRepo,
-		http:       http,
-		cfg:        cfg,
+		filesRepo:   filesRepo,
+		tokensRepo:  tokensRepo,
+		syncsRepo:   syncsRepo,
+		rekeyRepo:   rekeyRepo,
+		stageRepo:   stageRepo,
+		profileRepo: profileRepo,
+		index:       index.New(cfg.Username, filesRepo, indexRepo),
+		http:        http,
+		cfg:         cfg,
+	}
+}
+
+// key получает из мастер-пароля и ключевого файла ключ авторизации и ключ
+// хранилища. Серверу передаётся только ключ авторизации.
+func (a *App) key(Username string, Password string) (*masterkey.Key, error) {
+	params, err := a.accountParams(Username)
+	if err != nil {
+		return nil, err
+	}
+	return a.deriveKey(Username, Password, a.cfg.KeyFile, params)
+}
+
+func (a *App) deriveKey(Username string, Password string, KeyFile string, params masterkey.Params) (*masterkey.Key, error) {
+	var keyFile []byte
+	if KeyFile != "" {
+		var err error
+		if keyFile, err = masterkey.ReadKeyFile(KeyFile); err != nil {
+			return nil, err
+		}
+	}
+	return masterkey.Derive(Username, Password, params, keyFile)
+}
+
+// kdfParams возвращает параметры Argon2id из настроек и параметры, с которыми
+// получены ключи учётной записи. Пока параметры не сохранены (до первого
+// входа с этой версией клиента), ими считаются параметры из настроек.
+func (a *App) kdfParams(Username string) (masterkey.Params, masterkey.Params, error) {
+	configured := masterkey.Params{
+		Time:    a.cfg.KDFTime,
+		Memory:  a.cfg.KDFMemory,
+		Threads: a.cfg.KDFThreads,
+	}.WithDefaults()
+	p, err := profile.Load(context.Background(), Username, a.profileRepo)
+	if err != nil {
+		return masterkey.Params{}, masterkey.Params{}, err
+	}
+	if p == nil {
+		return configured, configured, nil
+	}
+	return configured, p.KDF, nil
+}
+
+// accountParams возвращает параметры Argon2id учётной записи. С другими
+// параметрами не совпадут ни ключ авторизации, ни ключ хранилища, поэтому
+// изменённые в настройках параметры считаются ошибкой: сменить их можно
+// только командой rekey.
+func (a *App) accountParams(Username string) (masterkey.Params, error) {
+	configured, saved, err := a.kdfParams(Username)
+	if err != nil {
+		return masterkey.Params{}, err
+	}
+	if configured != saved {
+		return masterkey.Params{}, fmt.Errorf(
+			"%w: в настройках time=%d memory=%d threads=%d, для %s сохранены time=%d memory=%d threads=%d. "+
+				"Верните прежние kdf_time, kdf_memory и kdf_threads или смените параметры командой rekey",
+			apperrors.ErrKDFParamsChanged,
+			configured.Time, configured.Memory, configured.Threads,
+			Username, saved.Time, saved.Memory, saved.Threads,
+		)
+	}
+	return saved, nil
+}
+
+// saveParams запоминает параметры Argon2id, с которыми сервер принял ключ
+// авторизации.
+func (a *App) saveParams(Username string, params masterkey.Params) error {
+	return profile.Save(context.Background(), Username, profile.Profile{KDF: params}, a.profileRepo)
+}
+
+// labels возвращает описание, категорию и метки записи из командной строки.
+func (a *App) labels() entities.Labels {
+	return entities.NewLabels(a.cfg.Description, a.cfg.Category, a.cfg.Tag)
+}
+
+// upload отправляет файл на сервер с метками из его зашифрованных
+// метаданных. Без ключа, например при завершении прерванной смены пароля,
+// и для файлов без метаданных файл отправляется без меток.
+func (a *App) upload(filename string, key *masterkey.Key) error {
+	var labels entities.Labels
+	if key != nil {
+		if meta, err := a.filesRepo.ReadMetadata(filename, key); err == nil {
+			labels = meta.Labels
+		}
+	}
+	return upload.Run(a.filesRepo, filename, labels, a.http)
+}
+
+// updateCredential заменяет ключ авторизации пользователя на сервере.
+func (a *App) updateCredential(auth string) error {
+	return password.Run(a.cfg.Username, auth, a.http)
+}
+
+// keyError поясняет ошибку неверного пароля: ключ хранилища зависит
+// и от ключевого файла, и по ошибке нельзя понять, что из них неверно.
+func (a *App) keyError(err error) error {
+	if !errors.Is(err, apperrors.ErrWrongPassword) {
+		return err
+	}
+	if a.cfg.KeyFile != "" {
+		return fmt.Errorf("%w: неверный пароль или ключевой файл %s", err, a.cfg.KeyFile)
 	}
+	return fmt.Errorf("%w: если хранилище защищено ключевым файлом, укажите его (--keyfile)", err)
 }
 
 func (a *App) Register(Username string, Password string, Email string) error {
-	return register.Run(Username, Password, Email, a.http)
+	params, err := a.accountParams(Username)
+	if err != nil {
+		return err
+	}
+	// Ключ авторизации не зависит от ключевого файла
+	key, err := a.deriveKey(Username, Password, "", params)
+	if err != nil {
+		return err
+	}
+	if err := register.Run(Username, key.Auth, Email, a.http); err != nil {
+		return err
+	}
+	return a.saveParams(Username, params)
 }
 func (a *App) Login(Username string, Password string) error {
-	return login.Run(Username, Password, a.tokensRepo, a.http)
+	params, err := a.accountParams(Username)
+	if err != nil {
+		return err
+	}
+	key, err := a.deriveKey(Username, Password, "", params)
+	if err != nil {
+		return err
+	}
+	if err := login.Run(Username, key.Auth, a.tokensRepo, a.http); err != nil {
+		return err
+	}
+	return a.saveParams(Username, params)
+}
+
+// LegacyLogin переводит учётную запись, зарегистрированную прежней версией
+// клиента, на ключ авторизации: входит с паролем, как прежние версии,
+// и заменяет пароль на сервере ключом авторизации. После этого сервер
+// пароль больше не получает, а вход выполняется командой login.
+func (a *App) LegacyLogin(Username string, Password string) error {
+	params, err := a.accountParams(Username)
+	if err != nil {
+		return err
+	}
+	key, err := a.deriveKey(Username, Password, "", params)
+	if err != nil {
+		return err
+	}
+	if err := login.Run(Username, Password, a.tokensRepo, a.http); err != nil {
+		return err
+	}
+
+	// Замена пароля подтверждается токеном, полученным при входе
+	token, err := a.tokensRepo.GetFileContent(context.Background(), Username)
+	if err != nil {
+		return err
+	}
+	a.http.SetHeader("Authorization", "Bearer "+token)
+	if err := a.updateCredential(key.Auth); err != nil {
+		return fmt.Errorf("вход выполнен, но пароль на сервере не заменён ключом авторизации, повторите login --legacy: %w", err)
+	}
+	return a.saveParams(Username, params)
 }
 func (a *App) Encrypt(Password string, Input string, Output string) error {
-	err := encrypt.Run(Password, Input, Output, a.filesRepo)
-	if err == nil {
-		err = upload.Run(a.filesRepo, Output, "", a.http)
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
 	}
-	return err
+	id, err := encrypt.Run(key, Input, Output, a.labels(), a.filesRepo, a.index)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(id, key)
 }
 func (a *App) Decrypt(Password string, Input string, Output string) error {
-	return decrypt.Run(a.cfg.Password, a.cfg.Input, a.cfg.Output, a.filesRepo)
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	return a.keyError(decrypt.Run(key, Input, Output, a.filesRepo, a.index, a.http))
+}
+func (a *App) Upload(Output string) error {
+	return upload.Run(a.filesRepo, Output, a.labels(), a.http)
+}
+func (a *App) Download(Password string, Input string) error {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	return a.keyError(download.Run(key, Input, a.filesRepo, a.index, a.http))
+}
+
+// CheckCard проверяет поля карты без пароля и шифрования и возвращает
+// платёжную систему и признак истёкшего срока действия. Неверные поля
+// возвращаются в apperrors.FieldErrors.
+func (a *App) CheckCard(Number string, Date string, CVV string, PIN string) (string, bool, error) {
+	c := card.CardJSON{Number: Number, Date: Date, CVV: CVV, PIN: PIN}
+	if err := card.Check(&c); err != nil {
+		return "", false, err
+	}
+	return c.Brand, c.Expired(time.Now()), nil
+}
+
+// Card проверяет и шифрует карту и отправляет её на сервер. Поля
+// проверяются до вывода ключа из пароля.
+func (a *App) Card(Number string, Date string, CVV string, Holder string, Bank string, PIN string, Notes string, Password string) error {
+	c := card.CardJSON{Number: Number, Date: Date, CVV: CVV, Holder: Holder, Bank: Bank, PIN: PIN, Notes: Notes}
+	if err := card.Check(&c); err != nil {
+		return err
+	}
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	filename, err := card.Run(c, a.labels(), key, a.filesRepo, a.index, a.http)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(filename, key)
+}
+func (a *App) Readcard(Number string, Password string) error {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	return a.keyError(readcard.Run(Number, key, a.filesRepo, a.index))
+}
+
+// CredAdd шифрует новую запись учётных данных и отправляет её на сервер.
+func (a *App) CredAdd(Name string, URL string, Login string, Secret string, Notes string, Password string) error {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	cred := credential.CredentialJSON{URL: URL, Login: Login, Password: Secret, Notes: Notes}
+	filename, err := credential.Add(Name, cred, a.labels(), key, a.filesRepo, a.index)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(filename, key)
 }
-func (a *App) Upload(Output string, Category string) error {
-	return upload.Run(a.filesRepo, Output, Category, a.http)
+
+// CredGet возвращает расшифрованную запись учётных данных для вывода.
+func (a *App) CredGet(Name string, Password string) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	cred, err := credential.Get(Name, key, a.filesRepo, a.index)
+	if err != nil {
+		return "", a.keyError(err)
+	}
+	return credential.Format(cred), nil
+}
+
+// CredList возвращает имена записей учётных данных, по одному в строке.
+func (a *App) CredList(Password string) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	list, err := credential.List(key, a.index)
+	if err != nil {
+		return "", a.keyError(err)
+	}
+	return strings.Join(list, "\n"), nil
+}
+
+// CredUpdate изменяет заданные поля записи учётных данных и отправляет
+// её на сервер под прежним идентификатором. Поле nil не меняется, а пустая
+// строка очищает поле.
+func (a *App) CredUpdate(Name string, URL *string, Login *string, Secret *string, Notes *string, Password string) error {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	changes := credential.Changes{URL: URL, Login: Login, Password: Secret, Notes: Notes}
+	filename, err := credential.Update(Name, changes, a.labels(), key, a.filesRepo, a.index)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(filename, key)
 }
-func (a *App) Download(Input string) error {
-	return download.Run(Input, a.filesRepo, a.http)
+
+// NoteAdd сохраняет заметку из командной строки: Text "-" читается из
+// стандартного ввода, пустой Text вводится в редакторе $EDITOR.
+func (a *App) NoteAdd(Name string, Text string, Password string) error {
+	text, err := note.ReadText(Text, os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer clear(text)
+	return a.saveNote(Name, text, Password)
 }
-func (a *App) Card(Number string, Date string, CVV string, Password string) error {
-	filename, err := card.Run(Number, Date, CVV, Password, a.filesRepo, a.http)
-	if err == nil {
-		err = upload.Run(a.filesRepo, filename, "card", a.http)
+
+// NoteSave шифрует заметку с текстом Text и отправляет её на сервер.
+func (a *App) NoteSave(Name string, Text string, Password string) error {
+	return a.saveNote(Name, []byte(Text), Password)
+}
+
+func (a *App) saveNote(Name string, Text []byte, Password string) error {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	filename, err := note.Save(Name, Text, a.labels(), key, a.filesRepo, a.index)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(filename, key)
+}
+
+// NoteShow возвращает расшифрованный текст заметки.
+func (a *App) NoteShow(Name string, Password string) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	text, err := note.Show(Name, key, a.filesRepo, a.index)
+	if err != nil {
+		return "", a.keyError(err)
+	}
+	defer clear(text)
+	return string(text), nil
+}
+
+// TotpAdd сохраняет секрет TOTP из ссылки otpauth:// или, если ссылка
+// не задана, из секрета в base32 с параметрами.
+func (a *App) TotpAdd(Name string, URI string, Secret string, Algorithm string, Digits int, Period int, Password string) error {
+	var t *totp.TOTPJSON
+	var err error
+	if URI != "" {
+		t, err = totp.ParseURI(URI)
+	} else {
+		t, err = totp.New(Secret, Algorithm, Digits, Period)
+	}
+	if err != nil {
+		return err
+	}
+
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return err
+	}
+	filename, err := totp.Save(Name, t, a.labels(), key, a.filesRepo, a.index)
+	if err != nil {
+		return a.keyError(err)
+	}
+	return a.upload(filename, key)
+}
+
+// TotpCode возвращает текущий код TOTP и число секунд до его смены.
+func (a *App) TotpCode(Name string, Password string) (string, int, error) {
+	generate, err := a.TotpGenerator(Name, Password)
+	if err != nil {
+		return "", 0, err
+	}
+	code, remaining, err := generate(time.Now())
+	if err != nil {
+		return "", 0, err
+	}
+	return code, int(remaining / time.Second), nil
+}
+
+// TotpGenerator расшифровывает запись TOTP и возвращает функцию, которая
+// вычисляет код на заданный момент без повторной расшифровки. Секрет
+// остаётся в памяти, пока функция используется.
+func (a *App) TotpGenerator(Name string, Password string) (func(now time.Time) (string, time.Duration, error), error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return nil, err
 	}
-	return err
+	t, err := totp.Get(Name, key, a.filesRepo, a.index)
+	if err != nil {
+		return nil, a.keyError(err)
+	}
+	return t.Code, nil
 }
+
 func (a *App) Deletecard(Input string) error {
 	return nil
 }
+
+// Delete пока не поддерживается: удаление файлов на сервере не реализовано,
+// и команда не должна сообщать об успехе, ничего не удалив.
 func (a *App) Delete(Input string) error {
-	return download.Run(Input, a.filesRepo, a.http)
+	return fmt.Errorf("удаление %s: %w", Input, apperrors.ErrNotImplemented)
 }
 func (a *App) Sync(Username string) error {
 	// files4download, files4deletion,
-	return sync.Run(Username, a.filesRepo, a.syncsRepo, a.http)
+	filter := sync.Filter{Category: a.cfg.Category, Tags: a.labels().Tags}
+	return sync.Run(Username, filter, a.filesRepo, a.syncsRepo, a.index, a.http)
+}
+
+// Rekey перешифровывает хранилище новым паролем или ключевым файлом.
+// Прежний ключ получается с сохранёнными параметрами Argon2id, а новый —
+// с параметрами из настроек, поэтому Rekey меняет и параметры.
+func (a *App) Rekey(OldPassword string, NewPassword string) error {
+	configured, saved, err := a.kdfParams(a.cfg.Username)
+	if err != nil {
+		return err
+	}
+	// Пароли не нужны, если осталось только отправить файлы на сервер
+	var oldKey, newKey *masterkey.Key
+	if OldPassword != "" {
+		if oldKey, err = a.deriveKey(a.cfg.Username, OldPassword, a.cfg.KeyFile, saved); err != nil {
+			return err
+		}
+	}
+	if NewPassword != "" {
+		// Ключевой файл меняется вместе с паролем, если задан новый
+		keyFile := a.cfg.KeyFile
+		if a.cfg.NewKeyFile != "" {
+			keyFile = a.cfg.NewKeyFile
+		}
+		if newKey, err = a.deriveKey(a.cfg.Username, NewPassword, keyFile, configured); err != nil {
+			return err
+		}
+	}
+	return rekey.Run(a.cfg.Username, oldKey, newKey, a.filesRepo, a.stageRepo, a.rekeyRepo, a.profileRepo, func(name string) error {
+		return a.upload(name, newKey)
+	}, a.updateCredential)
+}
+
+// List возвращает записи хранилища с описанием, категорией и метками.
+// Записи отбираются по категории и меткам из командной строки и по
+// подстроке Query в имени или описании.
+func (a *App) List(Query string, Password string) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	filter := list.Filter{Category: a.cfg.Category, Tags: a.labels().Tags, Query: Query}
+	entries, err := list.Run(key, filter, a.index)
+	if err != nil {
+		return "", a.keyError(err)
+	}
+	return list.Format(entries), nil
+}
+
+// Verify проверяет все файлы хранилища и возвращает отчёт. Если есть
+// файлы, которые не расшифровываются, вместе с отчётом возвращается ошибка.
+func (a *App) Verify(Password string, JSON bool) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	report, err := verify.Run(key, a.filesRepo, a.index)
+	if err != nil {
+		return "", err
+	}
+	text, err := verify.Format(report, JSON)
+	if err != nil {
+		return "", err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return text, fmt.Errorf("проверка не пройдена, файлов с ошибками: %d", failed)
+	}
+	return text, nil
+}
+
+// Split разделяет ключ хранилища на доли и возвращает их либо, если задан
+// каталог Output, пути файлов с долями.
+func (a *App) Split(Password string, Shares int, Threshold int, Output string) (string, error) {
+	key, err := a.key(a.cfg.Username, Password)
+	if err != nil {
+		return "", err
+	}
+	shares, err := recovery.Split(key, Shares, Threshold)
+	if err != nil {
+		return "", err
+	}
+	if Output == "" {
+		return strings.Join(shares, "\n"), nil
+	}
+	paths, err := recovery.WriteShares(Output, shares)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(paths, "\n"), nil
+}
+
+// Recover восстанавливает ключ хранилища из долей и перешифровывает
+// хранилище новым паролем так же, как Rekey.
+func (a *App) Recover(Shares []string, NewPassword string) error {
+	journal, err := rekey.LoadJournal(context.Background(), a.cfg.Username, a.rekeyRepo)
+	if err != nil {
+		return err
+	}
+	// Прерванное восстановление продолжается без долей после переноса файлов
+	if journal != nil && journal.State != rekey.StatePrepare {
+		return a.Rekey("", "")
+	}
+
+	if NewPassword == "" {
+		return errors.New("empty new password")
+	}
+	oldKey, err := recovery.Recover(Shares, a.filesRepo)
+	if err != nil {
+		return err
+	}
+	keyFile := a.cfg.KeyFile
+	if a.cfg.NewKeyFile != "" {
+		keyFile = a.cfg.NewKeyFile
+	}
+	configured, _, err := a.kdfParams(a.cfg.Username)
+	if err != nil {
+		return err
+	}
+	newKey, err := a.deriveKey(a.cfg.Username, NewPassword, keyFile, configured)
+	if err != nil {
+		return err
+	}
+	return rekey.Run(a.cfg.Username, oldKey, newKey, a.filesRepo, a.stageRepo, a.rekeyRepo, a.profileRepo, func(name string) error {
+		return a.upload(name, newKey)
+	}, a.updateCredential)
+}
+func (a *App) RecoverRekey() error {
+	return rekey.Recover(a.cfg.Username, a.filesRepo, a.stageRepo, a.rekeyRepo, a.profileRepo)
 }
@@ -10,20 +10,50 @@ import (
 // EnvConfig holds all configuration parameters for the application.
 // Fields are tagged to support multiple configuration sources.
 type EnvConfig struct {
-	Username              string `mapstructure:"username"`                                              // Server address to listen on
-	Password              string `mapstructure:"password"`                                              // Server address to listen on
-	Email                 string `mapstructure:"email"`                                                 // Server address to listen on
-	ServerAddress         string `mapstructure:"server_address" env:"SERVER_ADDRESS"`                   // Server address to listen on
-	StoragePath           string `mapstructure:"storage_path" env:"STORAGE_PATH"`                       // Path to file storage
-	DefaultRequestTimeout int    `mapstructure:"default_request_timeout" env:"DEFAULT_REQUEST_TIMEOUT"` // Default request timeout in seconds
-	PublicCertFile        string `mapstructure:"public_cert_file" env:"PUBLIC_CERT_FILE"`
-	PrivateCertFile       string `mapstructure:"private_cert_file" env:"PRIVATE_CERT_FILE"`
-	LogLevel              string `mapstructure:"log_level" env:"LOG_LEVEL"`
-	Input                 string `mapstructure:"input"`
-	Output                string `mapstructure:"output"`
-	Number                string `mapstructure:"number"`
-	Date                  string `mapstructure:"date"`
-	CVV                   string `mapstructure:"cvv"`
+	Username              string   `mapstructure:"username"`                                              // Server address to listen on
+	Password              string   `mapstructure:"password"`                                              // Server address to listen on
+	NewPassword           string   `mapstructure:"new_password"`                                          // Новый пароль для rekey
+	Email                 string   `mapstructure:"email"`                                                 // Server address to listen on
+	ServerAddress         string   `mapstructure:"server_address" env:"SERVER_ADDRESS"`                   // Server address to listen on
+	StoragePath           string   `mapstructure:"storage_path" env:"STORAGE_PATH"`                       // Path to file storage
+	DefaultRequestTimeout int      `mapstructure:"default_request_timeout" env:"DEFAULT_REQUEST_TIMEOUT"` // Default request timeout in seconds
+	PublicCertFile        string   `mapstructure:"public_cert_file" env:"PUBLIC_CERT_FILE"`
+	PrivateCertFile       string   `mapstructure:"private_cert_file" env:"PRIVATE_CERT_FILE"`
+	LogLevel              string   `mapstructure:"log_level" env:"LOG_LEVEL"`
+	Input                 string   `mapstructure:"input"`
+	Output                string   `mapstructure:"output"`
+	Number                string   `mapstructure:"number"`
+	Date                  string   `mapstructure:"date"`
+	CVV                   string   `mapstructure:"cvv"`
+	Holder                string   `mapstructure:"holder"`                        // Владелец карты
+	Bank                  string   `mapstructure:"bank"`                          // Банк, выпустивший карту
+	PIN                   string   `mapstructure:"pin"`                           // PIN-код карты
+	KDFTime               uint32   `mapstructure:"kdf_time" env:"KDF_TIME"`       // Argon2id мастер-пароля: количество проходов
+	KDFMemory             uint32   `mapstructure:"kdf_memory" env:"KDF_MEMORY"`   // Argon2id: объём памяти в КиБ
+	KDFThreads            uint8    `mapstructure:"kdf_threads" env:"KDF_THREADS"` // Argon2id: степень параллелизма
+	Cipher                string   `mapstructure:"cipher" env:"CIPHER"`           // Шифр новых файлов: aes-256-gcm или xchacha20-poly1305
+	Compress              string   `mapstructure:"compress" env:"COMPRESS"`       // Сжатие новых файлов перед шифрованием: none, gzip или zstd
+	ChunkSize             int      `mapstructure:"chunk_size" env:"CHUNK_SIZE"`   // Размер блока новых файлов в байтах
+	Workers               int      `mapstructure:"workers" env:"WORKERS"`         // Число горутин шифрования, 0 — по числу процессоров
+	JSON                  bool     `mapstructure:"json"`                          // Вывод verify в формате JSON
+	KeyFile               string   `mapstructure:"keyfile" env:"KEYFILE"`         // Ключевой файл, второй фактор ключа хранилища
+	NewKeyFile            string   `mapstructure:"new_keyfile"`                   // Новый ключевой файл для rekey
+	Shares                int      `mapstructure:"shares"`                        // Количество долей ключа для split
+	Threshold             int      `mapstructure:"threshold"`                     // Количество долей, достаточное для recover
+	Share                 []string `mapstructure:"share"`                         // Доли ключа или файлы с долями для recover
+	FixPermissions        bool     `mapstructure:"fix_permissions"`               // Исправить права хранилища при запуске
+	URL                   string   `mapstructure:"url"`                           // Адрес сайта в учётных данных
+	Login                 string   `mapstructure:"login"`                         // Логин в учётных данных
+	Secret                string   `mapstructure:"secret"`                        // Пароль в учётных данных или секрет TOTP
+	Notes                 string   `mapstructure:"notes"`                         // Заметки к записи
+	Algorithm             string   `mapstructure:"algorithm"`                     // Алгоритм TOTP: SHA1, SHA256 или SHA512
+	Digits                int      `mapstructure:"digits"`                        // Число цифр кода TOTP
+	Period                int      `mapstructure:"period"`                        // Период смены кода TOTP в секундах
+	Description           string   `mapstructure:"description"`                   // Описание записи, видно серверу
+	Category              string   `mapstructure:"category"`                      // Категория записи или фильтр sync и list
+	Tag                   []string `mapstructure:"tag"`                           // Метки записи или фильтр sync и list
+	Legacy                bool     `mapstructure:"legacy"`                        // Вход с паролем, как в прежних версиях, для перевода учётной записи на ключ авторизации
+	Force                 bool     `mapstructure:"force"`                         // Заменять существующий файл при расшифровке под исходным именем
 }
 
 // config() initializes and returns the application configuration.
@@ -43,6 +73,13 @@ func NewConfig() EnvConfig {
 	viper.SetDefault("log_level", "info")
 	viper.SetDefault("storage_path", "./_storage")
 	viper.SetDefault("default_request_timeout", 15)
+	viper.SetDefault("kdf_time", 3)
+	viper.SetDefault("kdf_memory", 64*1024)
+	viper.SetDefault("kdf_threads", 4)
+	viper.SetDefault("cipher", "aes-256-gcm")
+	viper.SetDefault("compress", "none")
+	viper.SetDefault("chunk_size", 64<<10)
+	viper.SetDefault("workers", 0)
 
 	viper.ReadInConfig()
 
@@ -51,6 +88,7 @@ func NewConfig() EnvConfig {
 	// Define and parse command-line flags
 	pflag.StringP("username", "u", "", "Username")
 	pflag.StringP("password", "p", "", "Password")
+	pflag.String("new_password", "", "New password (rekey)")
 	pflag.StringP("email", "e", "", "Email")
 	pflag.StringP("server_address", "a", "", "Server address to listen on")
 	pflag.StringP("input", "i", "", "Input file (only name for decription)")
@@ -58,6 +96,32 @@ func NewConfig() EnvConfig {
 	pflag.StringP("number", "n", "", "Bank card number")
 	pflag.StringP("date", "d", "", "Bank card date")
 	pflag.StringP("cvv", "v", "", "Bank card cvv")
+	pflag.String("holder", "", "Bank card holder name")
+	pflag.String("bank", "", "Bank card issuer")
+	pflag.String("pin", "", "Bank card PIN")
+	pflag.String("cipher", "", "Cipher for new files: aes-256-gcm or xchacha20-poly1305")
+	pflag.String("compress", "", "Compression before encryption: none, gzip or zstd")
+	pflag.Int("chunk_size", 0, "Chunk size in bytes for new files")
+	pflag.Int("workers", 0, "Encryption goroutines, 0 means one per CPU")
+	pflag.Bool("json", false, "JSON output (verify)")
+	pflag.Bool("fix_permissions", false, "Restrict storage permissions to the current user")
+	pflag.String("keyfile", "", "Key file required together with the password")
+	pflag.String("new_keyfile", "", "New key file (rekey)")
+	pflag.Int("shares", 5, "Number of vault key shares (split)")
+	pflag.Int("threshold", 3, "Shares required to recover the vault key (split)")
+	pflag.StringSlice("share", nil, "Vault key share or file with a share (recover), repeatable")
+	pflag.String("url", "", "Credential URL")
+	pflag.StringP("login", "l", "", "Credential login")
+	pflag.String("secret", "", "Credential password or base32 TOTP secret")
+	pflag.String("notes", "", "Record notes")
+	pflag.String("algorithm", "", "TOTP algorithm: SHA1, SHA256 or SHA512 (default SHA1)")
+	pflag.Int("digits", 0, "TOTP code digits (default 6)")
+	pflag.Int("period", 0, "TOTP period in seconds (default 30)")
+	pflag.String("description", "", "Record description, visible to the server")
+	pflag.String("category", "", "Record category, or sync and list filter")
+	pflag.StringSlice("tag", nil, "Record tag, or sync and list filter, repeatable")
+	pflag.Bool("legacy", false, "Log in with the plain password once and switch the account to the derived auth key (login)")
+	pflag.Bool("force", false, "Overwrite an existing file when decrypting under the original name (decrypt)")
 	pflag.Parse()
 
 	viper.BindPFlags(pflag.CommandLine) // Flags override everything
@@ -22,6 +22,10 @@ func TestNewConfig(t *testing.T) {
 				StoragePath:           "./_storage",
 				DefaultRequestTimeout: 15,
 				LogLevel:              "info",
+				KDFTime:               3,
+				KDFMemory:             64 * 1024,
+				KDFThreads:            4,
+				Cipher:                "aes-256-gcm",
 			},
 		},
 		{
@@ -31,12 +35,20 @@ func TestNewConfig(t *testing.T) {
 				"STORAGE_PATH":            "/tmp/storage",
 				"DEFAULT_REQUEST_TIMEOUT": "30",
 				"LOG_LEVEL":               "debug",
+				"KDF_TIME":                "1",
+				"KDF_MEMORY":              "8192",
+				"KDF_THREADS":             "2",
+				"CIPHER":                  "xchacha20-poly1305",
 			},
 			expected: EnvConfig{
 				ServerAddress:         "http://test:8080",
 				StoragePath:           "/tmp/storage",
 				DefaultRequestTimeout: 30,
 				LogLevel:              "debug",
+				KDFTime:               1,
+				KDFMemory:             8192,
+				KDFThreads:            2,
+				Cipher:                "xchacha20-poly1305",
 			},
 		},
 	}
@@ -59,6 +71,10 @@ func TestNewConfig(t *testing.T) {
 			assert.Equal(t, tt.expected.StoragePath, config.StoragePath)
 			assert.Equal(t, tt.expected.DefaultRequestTimeout, config.DefaultRequestTimeout)
 			assert.Equal(t, tt.expected.LogLevel, config.LogLevel)
+			assert.Equal(t, tt.expected.KDFTime, config.KDFTime)
+			assert.Equal(t, tt.expected.KDFMemory, config.KDFMemory)
+			assert.Equal(t, tt.expected.KDFThreads, config.KDFThreads)
+			assert.Equal(t, tt.expected.Cipher, config.Cipher)
 		})
 	}
 }
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Metadata сведения о записи, которые хранятся в зашифрованном виде внутри
+// файла. Серверу, кроме зашифрованного файла, передаются только метки Labels.
+type Metadata struct {
+	Alias       string    `json:"alias,omitempty"`       // Имя записи, под которым её ищет пользователь
+	Name        string    `json:"name,omitempty"`        // Исходное имя файла
+	MIMEType    string    `json:"mime_type,omitempty"`   // Тип содержимого
+	Created     time.Time `json:"created"`               // Время создания записи
+	Modified    time.Time `json:"modified"`              // Время изменения исходного файла
+	Notes       string    `json:"notes,omitempty"`       // Заметки пользователя
+	Compression string    `json:"compression,omitempty"` // Алгоритм сжатия данных перед шифрованием
+	Labels
+}
+
+// Labels описание, категория и метки записи, которые задаёт пользователь.
+// Они передаются серверу в открытом виде, чтобы по ним работали фильтры
+// синхронизации.
+type Labels struct {
+	Description string   `json:"description,omitempty"` // Описание записи
+	Category    string   `json:"category,omitempty"`    // Категория, по умолчанию тип записи: card, credential, note, totp
+	Tags        []string `json:"tags,omitempty"`        // Метки
+}
+
+// NewLabels возвращает метки без пробелов по краям, пустых и повторяющихся
+// значений. Метки сортируются.
+func NewLabels(description string, category string, tags []string) Labels {
+	l := Labels{
+		Description: strings.TrimSpace(description),
+		Category:    strings.TrimSpace(category),
+	}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			l.Tags = append(l.Tags, tag)
+		}
+	}
+	slices.Sort(l.Tags)
+	l.Tags = slices.Compact(l.Tags)
+	return l
+}
+
+// OrCategory возвращает метки с категорией category, если категория
+// не задана пользователем.
+func (l Labels) OrCategory(category string) Labels {
+	if l.Category == "" {
+		l.Category = category
+	}
+	return l
+}
+
+// Merge возвращает метки, в которых заданные в changes значения заменяют
+// прежние.
+func (l Labels) Merge(changes Labels) Labels {
+	if changes.Description != "" {
+		l.Description = changes.Description
+	}
+	if changes.Category != "" {
+		l.Category = changes.Category
+	}
+	if len(changes.Tags) > 0 {
+		l.Tags = changes.Tags
+	}
+	return l
+}
+
+// HasTags сообщает, есть ли у записи все метки tags.
+func (l Labels) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(l.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
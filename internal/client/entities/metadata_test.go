@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLabels(t *testing.T) {
+	l := NewLabels("  отчёт ", " work ", []string{"tax", " 2024", "", "tax"})
+	assert.Equal(t, Labels{Description: "отчёт", Category: "work", Tags: []string{"2024", "tax"}}, l)
+	assert.Equal(t, Labels{}, NewLabels("", "", nil))
+}
+
+func TestLabels(t *testing.T) {
+	l := Labels{Description: "почта", Tags: []string{"2024", "tax"}}
+
+	assert.Equal(t, "card", l.OrCategory("card").Category)
+	assert.Equal(t, "work", Labels{Category: "work"}.OrCategory("card").Category)
+
+	merged := l.Merge(Labels{Category: "work"})
+	assert.Equal(t, Labels{Description: "почта", Category: "work", Tags: []string{"2024", "tax"}}, merged)
+	assert.Equal(t, []string{"home"}, l.Merge(Labels{Tags: []string{"home"}}).Tags)
+
+	assert.True(t, l.HasTags(nil))
+	assert.True(t, l.HasTags([]string{"tax", "2024"}))
+	assert.False(t, l.HasTags([]string{"tax", "2023"}))
+}
+
+func TestMetadataJSON(t *testing.T) {
+	// Метки хранятся на верхнем уровне, как категория в прежних версиях
+	data, err := json.Marshal(Metadata{Alias: "report.pdf", Labels: Labels{Category: "work", Tags: []string{"tax"}}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"alias":"report.pdf","created":"0001-01-01T00:00:00Z","modified":"0001-01-01T00:00:00Z","category":"work","tags":["tax"]}`, string(data))
+
+	var meta Metadata
+	require.NoError(t, json.Unmarshal([]byte(`{"alias":"card_1.json","category":"card"}`), &meta))
+	assert.Equal(t, "card", meta.Category)
+}
@@ -0,0 +1,72 @@
+package filestore
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Алгоритмы сжатия данных перед шифрованием. Алгоритм записывается
+// в зашифрованные метаданные файла.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// ValidateCompression проверяет, что алгоритм сжатия поддерживается.
+// Пустая строка означает отсутствие сжатия.
+func ValidateCompression(name string) error {
+	switch name {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("неизвестный алгоритм сжатия %q: ожидается %s, %s или %s", name, CompressionNone, CompressionGzip, CompressionZstd)
+}
+
+// nopWriteCloser пропускает данные без сжатия.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressor возвращает поток, сжимающий данные алгоритмом name.
+// Close дописывает остаток сжатых данных, но не закрывает w.
+func newCompressor(w io.Writer, name string) (io.WriteCloser, error) {
+	switch name {
+	case "", CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, ValidateCompression(name)
+}
+
+// newDecompressor возвращает поток распакованных данных. Алгоритм берётся
+// из метаданных файла, поэтому неизвестное значение означает файл более
+// новой версии клиента.
+func newDecompressor(r io.Reader, name string) (io.ReadCloser, error) {
+	switch name {
+	case "", CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки: %w", err)
+		}
+		return zr, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("%w: сжатие %q", apperrors.ErrUnsupportedFormat, name)
+}
@@ -1,42 +1,117 @@
 package filestore
 
 import (
+	"bufio"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/aube/keeper/internal/client/entities"
 	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/atomicfile"
+	"github.com/aube/keeper/internal/client/utils/cryptostream"
 	"github.com/aube/keeper/internal/client/utils/logger"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/aube/keeper/internal/client/utils/progress"
 	"github.com/rs/zerolog"
 )
 
+// Права каталогов и файлов хранилища: токены, индекс и зашифрованные файлы
+// доступны только владельцу. Расшифрованные файлы создаются с теми же правами.
 const (
-	chunkSize    = 4096 // Размер блока для чтения/шифрования
-	gcmNonceSize = 12   // Стандартный размер nonce для GCM
+	dirPerm  os.FileMode = 0700
+	filePerm os.FileMode = 0600
 )
 
 type FileSystemRepository struct {
 	storagePath string
+	cipherName  string
+	compression string
+	chunkSize   int
+	workers     int
+	overwrite   bool
 	mu          sync.RWMutex
 	log         zerolog.Logger
 }
 
-func NewFileSystemRepository(storagePath string) (*FileSystemRepository, error) {
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
+// Option настраивает FileSystemRepository при создании.
+type Option func(*FileSystemRepository)
+
+// WithCipher задаёт шифр для новых файлов: cryptostream.CipherAES256GCM или
+// cryptostream.CipherXChaCha20Poly1305. Файлы расшифровываются шифром из их
+// заголовка.
+func WithCipher(name string) Option {
+	return func(r *FileSystemRepository) {
+		if name != "" {
+			r.cipherName = name
+		}
+	}
+}
+
+// WithCompression задаёт алгоритм сжатия новых файлов перед шифрованием:
+// CompressionGzip, CompressionZstd или CompressionNone (по умолчанию).
+// Файлы распаковываются алгоритмом из их метаданных.
+func WithCompression(name string) Option {
+	return func(r *FileSystemRepository) {
+		if name != CompressionNone {
+			r.compression = name
+		}
+	}
+}
+
+// WithChunkSize задаёт размер блока открытого текста новых файлов.
+// Крупные блоки ускоряют шифрование больших файлов. Ноль означает
+// cryptostream.DefaultChunkSize.
+func WithChunkSize(size int) Option {
+	return func(r *FileSystemRepository) {
+		r.chunkSize = size
+	}
+}
+
+// WithWorkers задаёт число горутин, которые одновременно шифруют
+// и расшифровывают блоки файла. Ноль означает GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return func(r *FileSystemRepository) {
+		r.workers = n
+	}
+}
+
+// WithOverwrite разрешает заменять существующий файл, когда расшифрованный
+// файл сохраняется под исходным именем. Путь, заданный явно, заменяется
+// всегда.
+func WithOverwrite(overwrite bool) Option {
+	return func(r *FileSystemRepository) {
+		r.overwrite = overwrite
+	}
+}
+
+func NewFileSystemRepository(storagePath string, opts ...Option) (*FileSystemRepository, error) {
+	if err := os.MkdirAll(storagePath, dirPerm); err != nil {
 		return nil, err
 	}
-	return &FileSystemRepository{
+	r := &FileSystemRepository{
 		storagePath: storagePath,
+		cipherName:  cryptostream.CipherAES256GCM,
 		log:         logger.Get().With().Str("fs", "file_repository").Logger(),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := cryptostream.ValidateCipher(r.cipherName); err != nil {
+		return nil, err
+	}
+	if err := ValidateCompression(r.compression); err != nil {
+		return nil, err
+	}
+	if err := cryptostream.ValidateChunkSize(r.chunkSize); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *FileSystemRepository) GetPath(filename string) string {
@@ -48,20 +123,20 @@ func (r *FileSystemRepository) Save(ctx context.Context, filename string, data i
 	defer r.mu.Unlock()
 
 	filePath := r.GetPath(filename)
-	dst, err := os.Create(filePath)
+	dst, err := atomicfile.Create(filePath, filePerm)
 	if err != nil {
 		r.log.Debug().Err(err).Msg("Save")
 		r.log.Debug().Msg(filePath)
 		return err
 	}
-	defer dst.Close()
+	defer dst.Abort()
 
 	if _, err := io.Copy(dst, data); err != nil {
 		r.log.Debug().Err(err).Msg("Save")
 		return err
 	}
 
-	return nil
+	return dst.Commit()
 }
 
 func (r *FileSystemRepository) Delete(ctx context.Context, filename string) error {
@@ -132,7 +207,8 @@ func (r *FileSystemRepository) FindAll(ctx context.Context) (*entities.Files, er
 
 	var result entities.Files
 	for _, file := range files {
-		if file.IsDir() {
+		// Временные файлы остаются только после сбоя записи
+		if file.IsDir() || atomicfile.IsTemp(file.Name()) {
 			continue
 		}
 
@@ -151,181 +227,371 @@ func (r *FileSystemRepository) FindAll(ctx context.Context) (*entities.Files, er
 	return &result, nil
 }
 
-func (r *FileSystemRepository) EncryptFile(inputPath, outputName, password string) error {
-
-	fi, err := os.Stat(inputPath)
-	if err != nil {
+// Stdio вместо пути к файлу означает стандартный ввод для EncryptFile
+// и стандартный вывод для DecryptFile.
+const Stdio = "-"
+
+// EncryptFile шифрует файл inputPath в хранилище под именем outputName.
+// Метаданные дополняются исходным именем, типом и временем изменения файла
+// и сохраняются в зашифрованном виде. Если в метаданных не задан алгоритм
+// сжатия, данные сжимаются алгоритмом хранилища. Путь Stdio читает данные
+// из стандартного ввода.
+func (r *FileSystemRepository) EncryptFile(inputPath, outputName string, key *masterkey.Key, meta entities.Metadata) error {
+	if meta.Compression == "" {
+		meta.Compression = r.compression
+	}
+	if err := ValidateCompression(meta.Compression); err != nil {
 		return err
 	}
-	bar := progress.NewBar(fi.Size(), "Шифрую файл...")
+
+	var src io.Reader
+	var fi os.FileInfo
+	size := int64(-1) // размер стандартного ввода заранее неизвестен
+	if inputPath == Stdio {
+		src, inputPath = os.Stdin, ""
+	} else {
+		var err error
+		if fi, err = os.Stat(inputPath); err != nil {
+			return err
+		}
+		size = fi.Size()
+
+		// Открываем исходный файл
+		inputFile, err := os.Open(inputPath)
+		if err != nil {
+			return fmt.Errorf("не удалось открыть входной файл: %w", err)
+		}
+		defer inputFile.Close()
+		src = inputFile
+	}
+	bar := progress.NewBar(size, "Шифрую файл...")
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	outputPath := r.GetPath(outputName)
 
-	// Открываем исходный файл
-	inputFile, err := os.Open(inputPath)
+	// Начало файла нужно для определения типа содержимого
+	input := bufio.NewReaderSize(io.TeeReader(src, bar), sniffSize)
+	head, err := input.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+	metadata, err := json.Marshal(fillMetadata(meta, inputPath, fi, head))
 	if err != nil {
-		return fmt.Errorf("не удалось открыть входной файл: %w", err)
+		return err
 	}
-	defer inputFile.Close()
 
 	// Создаем выходной файл
-	outputFile, err := os.Create(outputPath)
+	outputFile, err := atomicfile.Create(outputPath, filePerm)
 	if err != nil {
 		return fmt.Errorf("не удалось создать выходной файл: %w", err)
 	}
-	defer outputFile.Close()
+	defer outputFile.Abort()
+
+	w, err := cryptostream.NewWriter(outputFile, key,
+		cryptostream.WithCipher(r.cipherName),
+		cryptostream.WithChunkSize(r.chunkSize),
+		cryptostream.WithWorkers(r.workers),
+		cryptostream.WithMetadata(metadata),
+	)
+	if err != nil {
+		return err
+	}
 
-	// Генерируем ключ из пароля
-	key := deriveKey(password)
+	// Сжимаем данные до шифрования: зашифрованные данные уже не сжимаются
+	cw, err := newCompressor(w, meta.Compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, input); err != nil {
+		return fmt.Errorf("ошибка шифрования: %w", err)
+	}
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("ошибка сжатия: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
 
-	// Инициализируем шифр
-	block, err := aes.NewCipher(key)
+	return outputFile.Commit()
+}
+
+// DecryptFile расшифровывает файл inputName из хранилища и возвращает путь
+// расшифрованного файла. Если outputPath пуст или указывает на каталог,
+// файл получает исходное имя, а существующий файл с этим именем не
+// заменяется без WithOverwrite. Время изменения восстанавливается из метаданных.
+// Путь Stdio выводит данные в стандартный вывод без индикатора прогресса.
+// Неверный пароль возвращает apperrors.ErrWrongPassword до создания
+// выходного файла.
+func (r *FileSystemRepository) DecryptFile(inputName, outputPath string, key *masterkey.Key) (string, error) {
+	inputPath := r.GetPath(inputName)
+
+	fi, err := os.Stat(inputPath)
 	if err != nil {
-		return fmt.Errorf("ошибка создания блока шифра: %w", err)
+		return "", err
+	}
+	var bar io.Writer = io.Discard
+	if outputPath != Stdio {
+		bar = progress.NewBar(fi.Size(), "Дешифрую файл...")
 	}
 
-	// Генерируем уникальный nonce
-	nonce := make([]byte, gcmNonceSize)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return fmt.Errorf("ошибка генерации nonce: %w", err)
+	// Открываем зашифрованный файл
+	inputFile, err := os.Open(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть входной файл: %w", err)
 	}
+	defer inputFile.Close()
 
-	// Записываем nonce в начало выходного файла
-	if _, err := outputFile.Write(nonce); err != nil {
-		return fmt.Errorf("ошибка записи nonce: %w", err)
+	return r.DecryptStream(io.TeeReader(inputFile, bar), outputPath, key)
+}
+
+// DecryptStream расшифровывает зашифрованный поток src, например загружаемый
+// с сервера, не сохраняя его в хранилище. Путь outputPath обрабатывается
+// так же, как в DecryptFile.
+func (r *FileSystemRepository) DecryptStream(src io.Reader, outputPath string, key *masterkey.Key) (string, error) {
+	plain, err := cryptostream.NewReader(src, key, cryptostream.WithWorkers(r.workers))
+	if err != nil {
+		return "", err
 	}
+	meta, err := parseMetadata(plain.Metadata())
+	if err != nil {
+		return "", err
+	}
+	data, err := newDecompressor(plain, meta.Compression)
+	if err != nil {
+		return "", err
+	}
+	defer data.Close()
 
-	// Создаем GCM режим
-	gcm, err := cipher.NewGCM(block)
+	if outputPath == Stdio {
+		if _, err := io.Copy(os.Stdout, data); err != nil {
+			return "", err
+		}
+		return Stdio, nil
+	}
+
+	outputPath, err = resolveOutputPath(outputPath, meta, r.overwrite)
 	if err != nil {
-		return fmt.Errorf("ошибка создания GCM: %w", err)
+		return "", err
 	}
 
-	// Буфер для чтения данных
-	buf := make([]byte, chunkSize)
+	// Расшифрованные данные появляются по итоговому пути только целиком:
+	// при ошибке открытый текст удаляется
+	outputFile, err := atomicfile.Create(outputPath, filePerm)
+	if err != nil {
+		return "", fmt.Errorf("не удалось создать выходной файл: %w", err)
+	}
+	defer outputFile.Abort()
 
-	for {
-		// Читаем порцию данных из файла
-		n, err := inputFile.Read(buf)
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("ошибка чтения файла: %w", err)
-		}
+	if _, err := io.Copy(outputFile, data); err != nil {
+		return "", err
+	}
+	if err := outputFile.Commit(); err != nil {
+		return "", err
+	}
 
-		if n == 0 {
-			break
+	if !meta.Modified.IsZero() {
+		if err := os.Chtimes(outputPath, time.Now(), meta.Modified); err != nil {
+			return "", err
 		}
+	}
+
+	return outputPath, nil
+}
+
+// WriteRecord шифрует небольшую запись data и сохраняет её под именем name.
+// Запись появляется в хранилище только целиком.
+func (r *FileSystemRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	if meta.Created.IsZero() {
+		meta.Created = time.Now().UTC()
+	}
+	metadata, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		// Шифруем данные
-		ciphertext := gcm.Seal(nil, nonce, buf[:n], nil)
+	outputFile, err := atomicfile.Create(r.GetPath(name), filePerm)
+	if err != nil {
+		return fmt.Errorf("не удалось создать выходной файл: %w", err)
+	}
+	defer outputFile.Abort()
 
-		// Записываем зашифрованные данные
-		if _, err := outputFile.Write(ciphertext); err != nil {
-			return fmt.Errorf("ошибка записи зашифрованных данных: %w", err)
+	w, err := cryptostream.NewWriter(outputFile, key,
+		cryptostream.WithCipher(r.cipherName),
+		cryptostream.WithMetadata(metadata),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("ошибка шифрования: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+// ReadRecord расшифровывает небольшую запись name целиком в память.
+func (r *FileSystemRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inputFile, err := os.Open(r.GetPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, apperrors.ErrFileNotFound
 		}
+		return nil, fmt.Errorf("не удалось открыть входной файл: %w", err)
+	}
+	defer inputFile.Close()
 
-		// Обновляем прогресс-бар
-		if err := bar.Add(n); err != nil {
-			return fmt.Errorf("ошибка обновления прогресс-бара: %w", err)
+	plain, err := cryptostream.NewReader(inputFile, key)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseMetadata(plain.Metadata())
+	if err != nil {
+		return nil, err
+	}
+	data, err := newDecompressor(plain, meta.Compression)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	return io.ReadAll(data)
+}
+
+// ReadMetadata расшифровывает только метаданные файла filename, не читая
+// его содержимое.
+func (r *FileSystemRepository) ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inputFile, err := os.Open(r.GetPath(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, apperrors.ErrFileNotFound
 		}
+		return nil, fmt.Errorf("не удалось открыть входной файл: %w", err)
 	}
+	defer inputFile.Close()
 
-	return nil
+	plain, err := cryptostream.NewReader(inputFile, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetadata(plain.Metadata())
 }
 
-func (r *FileSystemRepository) DecryptFile(inputName, outputPath, password string) error {
+// RekeyFile записывает в outputPath копию файла inputName, доступную по
+// новому ключу. Исходный файл не изменяется. Копия появляется в outputPath
+// только целиком, поэтому прерванная запись не оставляет обрезанных файлов.
+func (r *FileSystemRepository) RekeyFile(inputName, outputPath string, oldKey, newKey *masterkey.Key) error {
 	inputPath := r.GetPath(inputName)
 
 	fi, err := os.Stat(inputPath)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return apperrors.ErrFileNotFound
+		}
 		return err
 	}
-	bar := progress.NewBar(fi.Size(), "Дешифрую файл...")
+	bar := progress.NewBar(fi.Size(), "Перешифровываю "+inputName+"...")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// Открываем зашифрованный файл
 	inputFile, err := os.Open(inputPath)
 	if err != nil {
 		return fmt.Errorf("не удалось открыть входной файл: %w", err)
 	}
 	defer inputFile.Close()
 
-	// Создаем файл для расшифрованных данных
-	outputFile, err := os.Create(outputPath)
+	outputFile, err := atomicfile.Create(outputPath, filePerm)
 	if err != nil {
 		return fmt.Errorf("не удалось создать выходной файл: %w", err)
 	}
-	defer outputFile.Close()
+	defer outputFile.Abort()
 
-	// Получаем ключ из пароля
-	key := deriveKey(password)
-
-	// Инициализируем AES блок
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return fmt.Errorf("ошибка создания блока шифра: %w", err)
+	if err := cryptostream.Rewrap(io.TeeReader(inputFile, bar), outputFile, oldKey, newKey,
+		cryptostream.WithCipher(r.cipherName),
+		cryptostream.WithChunkSize(r.chunkSize),
+		cryptostream.WithWorkers(r.workers),
+	); err != nil {
+		return err
 	}
 
-	// Читаем nonce из начала файла
-	nonce := make([]byte, gcmNonceSize)
-	if _, err := io.ReadFull(inputFile, nonce); err != nil {
-		return fmt.Errorf("ошибка чтения nonce: %w", err)
-	}
+	return outputFile.Commit()
+}
 
-	// Создаем GCM режим
-	gcm, err := cipher.NewGCM(block)
+// CheckKey проверяет, что файл можно расшифровать ключом, не
+// расшифровывая его содержимое целиком.
+func (r *FileSystemRepository) CheckKey(filename string, key *masterkey.Key) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inputFile, err := os.Open(r.GetPath(filename))
 	if err != nil {
-		return fmt.Errorf("ошибка создания GCM: %w", err)
+		if os.IsNotExist(err) {
+			return apperrors.ErrFileNotFound
+		}
+		return fmt.Errorf("не удалось открыть входной файл: %w", err)
 	}
+	defer inputFile.Close()
 
-	// Буфер для чтения данных
-	buf := make([]byte, chunkSize+gcm.Overhead()) // Учитываем overhead аутентификации
+	return cryptostream.CheckKey(inputFile, key)
+}
 
-	for {
-		// Читаем порцию зашифрованных данных
-		n, err := inputFile.Read(buf)
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("ошибка чтения файла: %w", err)
-		}
+// NeedsPassword сообщает, что файл расшифровывается только мастер-паролем,
+// а не ключом хранилища.
+func (r *FileSystemRepository) NeedsPassword(filename string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-		if n == 0 {
-			break
+	inputFile, err := os.Open(r.GetPath(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, apperrors.ErrFileNotFound
 		}
+		return false, fmt.Errorf("не удалось открыть входной файл: %w", err)
+	}
+	defer inputFile.Close()
 
-		// Расшифровываем данные
-		plaintext, err := gcm.Open(nil, nonce, buf[:n], nil)
-		if err != nil {
-			return fmt.Errorf("ошибка дешифрования: %w", err)
-		}
+	return cryptostream.NeedsPassword(inputFile)
+}
 
-		// Записываем расшифрованные данные
-		if _, err := outputFile.Write(plaintext); err != nil {
-			return fmt.Errorf("ошибка записи данных: %w", err)
-		}
+// VerifyFile проверяет подлинность файла целиком, не записывая открытый
+// текст, и сообщает, записан ли файл в прежнем формате.
+func (r *FileSystemRepository) VerifyFile(filename string, key *masterkey.Key) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-		// Обновляем прогресс-бар
-		if err := bar.Add(n); err != nil {
-			return fmt.Errorf("ошибка обновления прогресс-бара: %w", err)
+	inputFile, err := os.Open(r.GetPath(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, apperrors.ErrFileNotFound
 		}
+		return false, fmt.Errorf("не удалось открыть входной файл: %w", err)
 	}
+	defer inputFile.Close()
 
-	return nil
+	return cryptostream.Verify(inputFile, key)
 }
 
-// var _ appFile.FileRepository = (*FileSystemRepository)(nil)
-
-func deriveKey(password string) []byte {
-	// В реальном приложении используйте PBKDF2, scrypt или аналогичные функции
-	// для безопасного преобразования пароля в ключ
-	key := make([]byte, 32) // AES-256 требует 32-байтный ключ
-	copy(key, password)
-
-	// Если пароль короче 32 байт, оставшиеся байты останутся нулевыми
-	// Если длиннее - обрежем
-	if len(key) > 32 {
-		key = key[:32]
-	}
+// ReplaceFile атомарно заменяет файл filename файлом sourcePath, который
+// должен находиться на той же файловой системе.
+func (r *FileSystemRepository) ReplaceFile(filename, sourcePath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	return key
+	return os.Rename(sourcePath, r.GetPath(filename))
 }
+
+// var _ appFile.FileRepository = (*FileSystemRepository)(nil)
@@ -1,13 +1,26 @@
 package filestore
 
 import (
+	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/cryptostream"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -90,14 +103,14 @@ func TestFileSystemRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Encrypt
-		password := "securepassword123"
+		key := testKey(t, "securepassword123")
 		encryptedName := "encrypted.dat"
-		err = repo.EncryptFile(inputFile, encryptedName, password)
+		err = repo.EncryptFile(inputFile, encryptedName, key, entities.Metadata{})
 		assert.NoError(t, err)
 
 		// Decrypt to new file
 		outputFile := filepath.Join(tempDir, "decrypted.txt")
-		err = repo.DecryptFile(encryptedName, outputFile, password)
+		_, err = repo.DecryptFile(encryptedName, outputFile, key)
 		assert.NoError(t, err)
 
 		// Verify decrypted content
@@ -107,7 +120,645 @@ func TestFileSystemRepository(t *testing.T) {
 
 		// Test wrong password
 		wrongOutput := filepath.Join(tempDir, "wrong.txt")
-		err = repo.DecryptFile(encryptedName, wrongOutput, "wrongpassword")
+		_, err = repo.DecryptFile(encryptedName, wrongOutput, testKey(t, "wrongpassword"))
 		assert.Error(t, err)
 	})
+
+	t.Run("Encrypt produces unique files", func(t *testing.T) {
+		inputFile := filepath.Join(tempDir, "salted.txt")
+		err := os.WriteFile(inputFile, []byte("same content"), 0644)
+		assert.NoError(t, err)
+
+		key := testKey(t, "password")
+		err = repo.EncryptFile(inputFile, "salted1.dat", key, entities.Metadata{})
+		assert.NoError(t, err)
+		err = repo.EncryptFile(inputFile, "salted2.dat", key, entities.Metadata{})
+		assert.NoError(t, err)
+
+		first, err := os.ReadFile(repo.GetPath("salted1.dat"))
+		assert.NoError(t, err)
+		second, err := os.ReadFile(repo.GetPath("salted2.dat"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Decrypt restores name and mtime", func(t *testing.T) {
+		testContent := "report body"
+		inputFile := filepath.Join(tempDir, "report.txt")
+		require.NoError(t, os.WriteFile(inputFile, []byte(testContent), 0644))
+		modified := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+		require.NoError(t, os.Chtimes(inputFile, modified, modified))
+
+		key := testKey(t, "password")
+		err := repo.EncryptFile(inputFile, "opaque.dat", key, entities.Metadata{Notes: "квартальный отчёт"})
+		require.NoError(t, err)
+
+		// Ни имя, ни заметки не видны в зашифрованном файле
+		data, err := os.ReadFile(repo.GetPath("opaque.dat"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "report.txt")
+		assert.NotContains(t, string(data), "квартальный")
+
+		outputDir := filepath.Join(tempDir, "restored")
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		outputPath, err := repo.DecryptFile("opaque.dat", outputDir, key)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(outputDir, "report.txt"), outputPath)
+
+		decryptedContent, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		assert.Equal(t, testContent, string(decryptedContent))
+
+		fi, err := os.Stat(outputPath)
+		require.NoError(t, err)
+		assert.True(t, modified.Equal(fi.ModTime()))
+
+		// Явно заданный путь имеет приоритет
+		explicit := filepath.Join(tempDir, "explicit.txt")
+		outputPath, err = repo.DecryptFile("opaque.dat", explicit, key)
+		require.NoError(t, err)
+		assert.Equal(t, explicit, outputPath)
+
+		// Файл с исходным именем не заменяется без WithOverwrite
+		require.NoError(t, os.WriteFile(filepath.Join(outputDir, "report.txt"), []byte("local data"), 0600))
+		_, err = repo.DecryptFile("opaque.dat", outputDir, key)
+		assert.ErrorIs(t, err, apperrors.ErrFileExists)
+		localContent, err := os.ReadFile(filepath.Join(outputDir, "report.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "local data", string(localContent))
+
+		forced, err := NewFileSystemRepository(tempDir, WithOverwrite(true))
+		require.NoError(t, err)
+		_, err = forced.DecryptFile("opaque.dat", outputDir, key)
+		require.NoError(t, err)
+		decryptedContent, err = os.ReadFile(filepath.Join(outputDir, "report.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, testContent, string(decryptedContent))
+	})
+
+	t.Run("Encrypt from stdin and decrypt to stdout", func(t *testing.T) {
+		testContent := "piped content"
+		stdin, err := os.CreateTemp(tempDir, "stdin")
+		require.NoError(t, err)
+		_, err = stdin.WriteString(testContent)
+		require.NoError(t, err)
+		_, err = stdin.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		stdout, err := os.CreateTemp(tempDir, "stdout")
+		require.NoError(t, err)
+
+		origStdin, origStdout := os.Stdin, os.Stdout
+		os.Stdin, os.Stdout = stdin, stdout
+		defer func() { os.Stdin, os.Stdout = origStdin, origStdout }()
+
+		key := testKey(t, "password")
+		require.NoError(t, repo.EncryptFile(Stdio, "piped.dat", key, entities.Metadata{Alias: "piped"}))
+
+		// У стандартного ввода нет ни имени, ни времени изменения
+		meta, err := repo.ReadMetadata("piped.dat", key)
+		require.NoError(t, err)
+		assert.Empty(t, meta.Name)
+		assert.True(t, meta.Modified.IsZero())
+		assert.Equal(t, "text/plain; charset=utf-8", meta.MIMEType)
+
+		outputPath, err := repo.DecryptFile("piped.dat", Stdio, key)
+		require.NoError(t, err)
+		assert.Equal(t, Stdio, outputPath)
+
+		data, err := os.ReadFile(stdout.Name())
+		require.NoError(t, err)
+		assert.Equal(t, testContent, string(data))
+	})
+
+	t.Run("DecryptStream", func(t *testing.T) {
+		inputFile := filepath.Join(tempDir, "stream.txt")
+		require.NoError(t, os.WriteFile(inputFile, []byte("remote content"), 0644))
+
+		key := testKey(t, "password")
+		require.NoError(t, repo.EncryptFile(inputFile, "stream.dat", key, entities.Metadata{}))
+		encrypted, err := os.ReadFile(repo.GetPath("stream.dat"))
+		require.NoError(t, err)
+
+		outputPath := filepath.Join(tempDir, "stream-out.txt")
+		path, err := repo.DecryptStream(bytes.NewReader(encrypted), outputPath, key)
+		require.NoError(t, err)
+		assert.Equal(t, outputPath, path)
+
+		data, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		assert.Equal(t, "remote content", string(data))
+
+		_, err = repo.DecryptStream(bytes.NewReader(encrypted), outputPath, testKey(t, "wrong"))
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+	})
+
+	t.Run("WriteRecord and ReadRecord", func(t *testing.T) {
+		key := testKey(t, "password")
+		err := repo.WriteRecord("record.dat", []byte(`{"a":"b"}`), key, entities.Metadata{Alias: "secret-name"})
+		require.NoError(t, err)
+		assert.False(t, repo.Exists("record.dat.tmp"))
+
+		data, err := repo.ReadRecord("record.dat", key)
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":"b"}`, string(data))
+
+		meta, err := repo.ReadMetadata("record.dat", key)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-name", meta.Alias)
+		assert.False(t, meta.Created.IsZero())
+
+		_, err = repo.ReadRecord("record.dat", testKey(t, "wrong"))
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+
+		_, err = repo.ReadMetadata("missing.dat", key)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+
+	t.Run("VerifyFile", func(t *testing.T) {
+		key := testKey(t, "password")
+		require.NoError(t, repo.WriteRecord("verify.dat", []byte(strings.Repeat("x", 10000)), key, entities.Metadata{}))
+
+		legacy, err := repo.VerifyFile("verify.dat", key)
+		assert.NoError(t, err)
+		assert.False(t, legacy)
+
+		_, err = repo.VerifyFile("verify.dat", testKey(t, "wrong"))
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+
+		data, err := os.ReadFile(repo.GetPath("verify.dat"))
+		require.NoError(t, err)
+		data[len(data)-10] ^= 1
+		require.NoError(t, os.WriteFile(repo.GetPath("verify.dat"), data, 0644))
+		_, err = repo.VerifyFile("verify.dat", key)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+		assert.NotErrorIs(t, err, apperrors.ErrWrongPassword)
+
+		_, err = repo.VerifyFile("missing.dat", key)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+
+	t.Run("Decrypt legacy format", func(t *testing.T) {
+		testContent := strings.Repeat("legacy secret ", 1000)
+		password := "legacypassword"
+
+		err := repo.Save(ctx, "legacy.dat", bytes.NewReader(sealLegacy(t, testContent, password)))
+		require.NoError(t, err)
+
+		outputFile := filepath.Join(tempDir, "legacy.txt")
+		_, err = repo.DecryptFile("legacy.dat", outputFile, testKey(t, password))
+		assert.NoError(t, err)
+
+		decryptedContent, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, testContent, string(decryptedContent))
+	})
+
+	t.Run("Wrong password creates no output", func(t *testing.T) {
+		inputFile := filepath.Join(tempDir, "guarded.txt")
+		require.NoError(t, os.WriteFile(inputFile, []byte("guarded"), 0644))
+		require.NoError(t, repo.EncryptFile(inputFile, "guarded.dat", testKey(t, "password"), entities.Metadata{}))
+
+		for _, name := range []string{"guarded.dat", "legacy.dat"} {
+			outputFile := filepath.Join(tempDir, "wrong-"+name)
+			_, err := repo.DecryptFile(name, outputFile, testKey(t, "wrong"))
+			assert.ErrorIs(t, err, apperrors.ErrWrongPassword, name)
+			assert.NoFileExists(t, outputFile, name)
+
+			// Без пути файл получил бы исходное имя в текущем каталоге
+			outputDir := filepath.Join(tempDir, "wrong-dir")
+			require.NoError(t, os.MkdirAll(outputDir, 0755))
+			_, err = repo.DecryptFile(name, outputDir, testKey(t, "wrong"))
+			assert.ErrorIs(t, err, apperrors.ErrWrongPassword, name)
+			entries, err := os.ReadDir(outputDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries, name)
+		}
+	})
+
+	t.Run("Failed writes leave no files", func(t *testing.T) {
+		err := repo.Save(ctx, "broken.dat", io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.False(t, repo.Exists("broken.dat"))
+
+		// Повреждение в середине файла обнаруживается после начала записи
+		chunked, err := NewFileSystemRepository(tempDir, WithChunkSize(1024))
+		require.NoError(t, err)
+		inputFile := filepath.Join(tempDir, "partial.txt")
+		require.NoError(t, os.WriteFile(inputFile, bytes.Repeat([]byte("secret "), 1000), 0644))
+		key := testKey(t, "password")
+		require.NoError(t, chunked.EncryptFile(inputFile, "partial.dat", key, entities.Metadata{}))
+
+		data, err := os.ReadFile(chunked.GetPath("partial.dat"))
+		require.NoError(t, err)
+		data[len(data)-10] ^= 1
+		require.NoError(t, chunked.Save(ctx, "partial.dat", bytes.NewReader(data)))
+
+		outputDir := filepath.Join(tempDir, "partial-out")
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		_, err = chunked.DecryptFile("partial.dat", filepath.Join(outputDir, "partial.txt"), key)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+
+		// Частично расшифрованный текст удалён
+		entries, err := os.ReadDir(outputDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		// Временный файл, оставшийся после сбоя, не считается записанным
+		require.NoError(t, os.WriteFile(repo.GetPath(".stale.dat.123.tmp"), []byte("partial"), 0644))
+		files, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		for _, f := range *files {
+			assert.False(t, strings.HasSuffix(f.Name, ".tmp"), f.Name)
+		}
+		require.NoError(t, os.Remove(repo.GetPath(".stale.dat.123.tmp")))
+	})
+
+	t.Run("Rekey keeps content and changes password", func(t *testing.T) {
+		testContent := strings.Repeat("rekey me ", 1000)
+		inputFile := filepath.Join(tempDir, "rekey.txt")
+		err := os.WriteFile(inputFile, []byte(testContent), 0644)
+		require.NoError(t, err)
+		oldKey, newKey := testKey(t, "old"), testKey(t, "new")
+		require.NoError(t, repo.EncryptFile(inputFile, "rekey.dat", oldKey, entities.Metadata{}))
+
+		before, err := os.ReadFile(repo.GetPath("rekey.dat"))
+		require.NoError(t, err)
+
+		stagedPath := filepath.Join(tempDir, "rekey.staged")
+		err = repo.RekeyFile("rekey.dat", stagedPath, testKey(t, "wrong"), newKey)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+		assert.NoFileExists(t, stagedPath)
+
+		require.NoError(t, repo.RekeyFile("rekey.dat", stagedPath, oldKey, newKey))
+
+		// Исходный файл не меняется до замены
+		unchanged, err := os.ReadFile(repo.GetPath("rekey.dat"))
+		require.NoError(t, err)
+		assert.Equal(t, before, unchanged)
+
+		require.NoError(t, repo.ReplaceFile("rekey.dat", stagedPath))
+		assert.NoFileExists(t, stagedPath)
+
+		assert.ErrorIs(t, repo.CheckKey("rekey.dat", oldKey), apperrors.ErrDecryptFailed)
+		assert.NoError(t, repo.CheckKey("rekey.dat", newKey))
+
+		outputFile := filepath.Join(tempDir, "rekey-new.txt")
+		_, err = repo.DecryptFile("rekey.dat", outputFile, newKey)
+		require.NoError(t, err)
+		decryptedContent, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, testContent, string(decryptedContent))
+	})
+
+	t.Run("Rekey converts legacy format", func(t *testing.T) {
+		testContent := strings.Repeat("legacy rekey ", 1000)
+		require.NoError(t, repo.Save(ctx, "legacy-rekey.dat", bytes.NewReader(sealLegacy(t, testContent, "old"))))
+		oldKey, newKey := testKey(t, "old"), testKey(t, "new")
+
+		assert.NoError(t, repo.CheckKey("legacy-rekey.dat", oldKey))
+		assert.ErrorIs(t, repo.CheckKey("legacy-rekey.dat", newKey), apperrors.ErrDecryptFailed)
+
+		stagedPath := filepath.Join(tempDir, "legacy-rekey.staged")
+		needs, err := repo.NeedsPassword("legacy-rekey.dat")
+		require.NoError(t, err)
+		assert.True(t, needs)
+
+		require.NoError(t, repo.RekeyFile("legacy-rekey.dat", stagedPath, oldKey, newKey))
+		require.NoError(t, repo.ReplaceFile("legacy-rekey.dat", stagedPath))
+
+		needs, err = repo.NeedsPassword("legacy-rekey.dat")
+		require.NoError(t, err)
+		assert.False(t, needs)
+		_, err = repo.NeedsPassword("missing.dat")
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+
+		data, err := os.ReadFile(repo.GetPath("legacy-rekey.dat"))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(data, []byte("KEEP")))
+
+		outputFile := filepath.Join(tempDir, "legacy-rekey.txt")
+		_, err = repo.DecryptFile("legacy-rekey.dat", outputFile, newKey)
+		require.NoError(t, err)
+		decryptedContent, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, testContent, string(decryptedContent))
+	})
+}
+
+// testKDFParams ускоряют тесты, стойкость здесь не важна.
+var testKDFParams = masterkey.Params{Time: 1, Memory: 1024, Threads: 1}
+
+// testKey получает ключи тестового пользователя из пароля.
+func testKey(t testing.TB, password string) *masterkey.Key {
+	key, err := masterkey.Derive("user", password, testKDFParams, nil)
+	require.NoError(t, err)
+	return key
+}
+
+// sealLegacy шифрует данные в формате без заголовка:
+// nonce || блоки AES-GCM по 4096 байт с одним nonce, ключ — пароль,
+// дополненный нулями.
+func sealLegacy(t *testing.T, content, password string) []byte {
+	key := make([]byte, 32)
+	copy(key, password)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	legacy := append([]byte{}, nonce...)
+	plain := []byte(content)
+	for len(plain) > 0 {
+		n := min(len(plain), 4096)
+		legacy = append(legacy, gcm.Seal(nil, nonce, plain[:n], nil)...)
+		plain = plain[n:]
+	}
+	return legacy
+}
+
+func TestCipherSuites(t *testing.T) {
+	tempDir := t.TempDir()
+	key := testKey(t, "password")
+
+	// Репозиторий с шифром по умолчанию читает файлы любого шифра
+	defaultRepo, err := NewFileSystemRepository(tempDir)
+	require.NoError(t, err)
+
+	testContent := strings.Repeat("cipher suite content ", 500)
+	inputFile := filepath.Join(tempDir, "plain.txt")
+	require.NoError(t, os.WriteFile(inputFile, []byte(testContent), 0644))
+
+	for _, name := range []string{cryptostream.CipherAES256GCM, cryptostream.CipherXChaCha20Poly1305} {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewFileSystemRepository(tempDir, WithCipher(name))
+			require.NoError(t, err)
+
+			encryptedName := name + ".dat"
+			require.NoError(t, repo.EncryptFile(inputFile, encryptedName, key, entities.Metadata{}))
+
+			outputFile := filepath.Join(tempDir, name+".txt")
+			_, err = defaultRepo.DecryptFile(encryptedName, outputFile, key)
+			require.NoError(t, err)
+
+			decryptedContent, err := os.ReadFile(outputFile)
+			require.NoError(t, err)
+			assert.Equal(t, testContent, string(decryptedContent))
+		})
+	}
+
+	t.Run("unknown cipher", func(t *testing.T) {
+		_, err := NewFileSystemRepository(tempDir, WithCipher("rot13"))
+		assert.Error(t, err)
+	})
+}
+
+func TestCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	key := testKey(t, "password")
+
+	// Репозиторий без сжатия распаковывает файлы по метаданным
+	defaultRepo, err := NewFileSystemRepository(tempDir)
+	require.NoError(t, err)
+
+	testContent := strings.Repeat(`{"level":"info","msg":"compressible log line"}`+"\n", 2000)
+	inputFile := filepath.Join(tempDir, "app.log")
+	require.NoError(t, os.WriteFile(inputFile, []byte(testContent), 0644))
+
+	require.NoError(t, defaultRepo.EncryptFile(inputFile, "plain.dat", key, entities.Metadata{}))
+	plainInfo, err := os.Stat(defaultRepo.GetPath("plain.dat"))
+	require.NoError(t, err)
+
+	for _, name := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewFileSystemRepository(tempDir, WithCompression(name))
+			require.NoError(t, err)
+
+			encryptedName := name + ".dat"
+			require.NoError(t, repo.EncryptFile(inputFile, encryptedName, key, entities.Metadata{}))
+
+			fi, err := os.Stat(repo.GetPath(encryptedName))
+			require.NoError(t, err)
+			assert.Less(t, fi.Size(), plainInfo.Size()/10)
+
+			meta, err := defaultRepo.ReadMetadata(encryptedName, key)
+			require.NoError(t, err)
+			assert.Equal(t, name, meta.Compression)
+
+			outputFile := filepath.Join(tempDir, name+".log")
+			_, err = defaultRepo.DecryptFile(encryptedName, outputFile, key)
+			require.NoError(t, err)
+
+			decryptedContent, err := os.ReadFile(outputFile)
+			require.NoError(t, err)
+			assert.Equal(t, testContent, string(decryptedContent))
+
+			data, err := defaultRepo.ReadRecord(encryptedName, key)
+			require.NoError(t, err)
+			assert.Equal(t, testContent, string(data))
+		})
+	}
+
+	t.Run("per file override", func(t *testing.T) {
+		repo, err := NewFileSystemRepository(tempDir, WithCompression(CompressionGzip))
+		require.NoError(t, err)
+
+		require.NoError(t, repo.EncryptFile(inputFile, "override.dat", key, entities.Metadata{Compression: CompressionZstd}))
+		meta, err := repo.ReadMetadata("override.dat", key)
+		require.NoError(t, err)
+		assert.Equal(t, CompressionZstd, meta.Compression)
+
+		assert.Error(t, repo.EncryptFile(inputFile, "bad.dat", key, entities.Metadata{Compression: "lzma"}))
+	})
+
+	t.Run("unknown compression", func(t *testing.T) {
+		_, err := NewFileSystemRepository(tempDir, WithCompression("lzma"))
+		assert.Error(t, err)
+
+		_, err = NewFileSystemRepository(tempDir, WithCompression(CompressionNone))
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown compression in metadata", func(t *testing.T) {
+		_, err := newDecompressor(strings.NewReader(""), "lzma")
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat)
+	})
+}
+
+func TestResolveOutputPath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		outputPath string
+		original   string
+		overwrite  bool
+		want       string
+		wantErr    bool
+	}{
+		{name: "explicit file", outputPath: filepath.Join(dir, "out.txt"), original: "report.txt", want: filepath.Join(dir, "out.txt")},
+		{name: "directory", outputPath: dir, original: "report.txt", want: filepath.Join(dir, "report.txt")},
+		{name: "empty path", outputPath: "", original: "report.txt", want: "report.txt"},
+		{name: "traversal", outputPath: dir, original: "../../etc/passwd", want: filepath.Join(dir, "passwd")},
+		{name: "unknown name", outputPath: dir, original: "", wantErr: true},
+		{name: "root name", outputPath: dir, original: "/", wantErr: true},
+		{name: "existing original name", outputPath: dir, original: "existing.txt", wantErr: true},
+		{name: "existing original name with force", outputPath: dir, original: "existing.txt", overwrite: true, want: filepath.Join(dir, "existing.txt")},
+		{name: "existing explicit file", outputPath: filepath.Join(dir, "existing.txt"), original: "report.txt", want: filepath.Join(dir, "existing.txt")},
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("local data"), 0600))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveOutputPath(tt.outputPath, &entities.Metadata{Name: tt.original}, tt.overwrite)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChunkOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	plain := make([]byte, 300<<10)
+	_, err := rand.Read(plain)
+	require.NoError(t, err)
+	inputFile := filepath.Join(tempDir, "input.bin")
+	require.NoError(t, os.WriteFile(inputFile, plain, 0644))
+	key := testKey(t, "password")
+
+	for _, tt := range []struct {
+		chunkSize int
+		workers   int
+	}{
+		{0, 0},
+		{1 << 10, 1},
+		{1 << 10, 4},
+		{100 << 10, 3},
+	} {
+		t.Run(fmt.Sprintf("chunk %d workers %d", tt.chunkSize, tt.workers), func(t *testing.T) {
+			repo, err := NewFileSystemRepository(tempDir, WithChunkSize(tt.chunkSize), WithWorkers(tt.workers))
+			require.NoError(t, err)
+
+			require.NoError(t, repo.EncryptFile(inputFile, "chunked.dat", key, entities.Metadata{}))
+
+			// Файл расшифровывается при любом числе горутин
+			other, err := NewFileSystemRepository(tempDir, WithWorkers(2))
+			require.NoError(t, err)
+			outputPath, err := other.DecryptFile("chunked.dat", filepath.Join(tempDir, "output.bin"), key)
+			require.NoError(t, err)
+
+			decrypted, err := os.ReadFile(outputPath)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(plain, decrypted))
+		})
+	}
+
+	t.Run("invalid chunk size", func(t *testing.T) {
+		_, err := NewFileSystemRepository(tempDir, WithChunkSize(-1))
+		assert.Error(t, err)
+		_, err = NewFileSystemRepository(tempDir, WithChunkSize(32<<20))
+		assert.Error(t, err)
+	})
+}
+
+// BenchmarkEncryptFile сравнивает шифрование и расшифровку большого файла
+// одной горутиной и GOMAXPROCS горутинами при разном размере блока:
+//
+//	go test -run '^$' -bench . ./internal/client/infrastructure/filestore/
+func BenchmarkEncryptFile(b *testing.B) {
+	const size = 32 << 20
+
+	tempDir := b.TempDir()
+	plain := make([]byte, size)
+	_, err := rand.Read(plain)
+	require.NoError(b, err)
+	inputFile := filepath.Join(tempDir, "input.bin")
+	require.NoError(b, os.WriteFile(inputFile, plain, 0644))
+	outputPath := filepath.Join(tempDir, "output.bin")
+	key := testKey(b, "password")
+
+	for _, chunkSize := range []int{4 << 10, 64 << 10, 1 << 20} {
+		for _, workers := range slices.Compact([]int{1, runtime.GOMAXPROCS(0)}) {
+			repo, err := NewFileSystemRepository(tempDir, WithChunkSize(chunkSize), WithWorkers(workers))
+			require.NoError(b, err)
+			name := fmt.Sprintf("chunk=%dK/workers=%d", chunkSize>>10, workers)
+
+			b.Run("encrypt/"+name, func(b *testing.B) {
+				b.SetBytes(size)
+				for range b.N {
+					require.NoError(b, repo.EncryptFile(inputFile, "bench.dat", key, entities.Metadata{}))
+				}
+			})
+
+			b.Run("decrypt/"+name, func(b *testing.B) {
+				require.NoError(b, repo.EncryptFile(inputFile, "bench.dat", key, entities.Metadata{}))
+				b.SetBytes(size)
+				b.ResetTimer()
+				for range b.N {
+					_, err := repo.DecryptFile("bench.dat", outputPath, key)
+					require.NoError(b, err)
+				}
+			})
+		}
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("права доступа в Windows задаются ACL")
+	}
+
+	storagePath := filepath.Join(t.TempDir(), "storage")
+	require.NoError(t, CheckPermissions(storagePath), "отсутствующее хранилище")
+
+	repo, err := NewFileSystemRepository(filepath.Join(storagePath, "tokens"))
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(context.Background(), "user", strings.NewReader("token")))
+
+	for _, path := range []string{storagePath, repo.GetPath("")} {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, dirPerm, fi.Mode().Perm(), path)
+	}
+	fi, err := os.Stat(repo.GetPath("user"))
+	require.NoError(t, err)
+	assert.Equal(t, filePerm, fi.Mode().Perm())
+	require.NoError(t, CheckPermissions(storagePath))
+
+	// Хранилище, созданное прежними версиями клиента
+	require.NoError(t, os.Chmod(repo.GetPath(""), 0755))
+	require.NoError(t, os.Chmod(repo.GetPath("user"), 0644))
+	err = CheckPermissions(storagePath)
+	assert.ErrorIs(t, err, apperrors.ErrInsecureStorage)
+	assert.ErrorContains(t, err, repo.GetPath("user"))
+
+	require.NoError(t, FixPermissions(storagePath))
+	require.NoError(t, CheckPermissions(storagePath))
+	fi, err = os.Stat(repo.GetPath("user"))
+	require.NoError(t, err)
+	assert.Equal(t, filePerm, fi.Mode().Perm())
+
+	// Каталог хранилища задан символической ссылкой: проверяется то,
+	// на что она указывает, а ссылки внутри хранилища пропускаются
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(storagePath, link))
+	require.NoError(t, os.Symlink(repo.GetPath("user"), filepath.Join(storagePath, "alias")))
+	require.NoError(t, CheckPermissions(link))
+
+	require.NoError(t, os.Chmod(repo.GetPath("user"), 0644))
+	err = CheckPermissions(link)
+	assert.ErrorIs(t, err, apperrors.ErrInsecureStorage)
+	assert.ErrorContains(t, err, filepath.Join(link, "tokens", "user"))
+
+	require.NoError(t, FixPermissions(link))
+	require.NoError(t, CheckPermissions(link))
+	fi, err = os.Stat(repo.GetPath("user"))
+	require.NoError(t, err)
+	assert.Equal(t, filePerm, fi.Mode().Perm())
 }
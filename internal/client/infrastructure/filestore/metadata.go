@@ -0,0 +1,78 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// sniffSize объём начала файла, по которому определяется тип содержимого.
+const sniffSize = 512
+
+// fillMetadata дополняет метаданные сведениями об исходном файле.
+// Заданные вызывающим поля не меняются. Для стандартного ввода inputPath
+// пуст, а fi равен nil.
+func fillMetadata(meta entities.Metadata, inputPath string, fi os.FileInfo, head []byte) entities.Metadata {
+	if meta.Name == "" && inputPath != "" {
+		meta.Name = filepath.Base(inputPath)
+	}
+	if meta.MIMEType == "" {
+		meta.MIMEType = mime.TypeByExtension(filepath.Ext(meta.Name))
+	}
+	if meta.MIMEType == "" {
+		meta.MIMEType = http.DetectContentType(head)
+	}
+	if meta.Created.IsZero() {
+		meta.Created = time.Now().UTC()
+	}
+	if meta.Modified.IsZero() && fi != nil {
+		meta.Modified = fi.ModTime().UTC()
+	}
+	return meta
+}
+
+// parseMetadata разбирает метаданные из контейнера. Файлы, записанные без
+// метаданных, возвращают пустую структуру.
+func parseMetadata(data []byte) (*entities.Metadata, error) {
+	var meta entities.Metadata
+	if len(data) == 0 {
+		return &meta, nil
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %v", err)
+	}
+	return &meta, nil
+}
+
+// resolveOutputPath возвращает путь расшифрованного файла. Если путь не задан
+// или указывает на каталог, файл получает исходное имя из метаданных. Такой
+// путь выбран не пользователем, поэтому существующий файл заменяется только
+// при overwrite.
+func resolveOutputPath(outputPath string, meta *entities.Metadata, overwrite bool) (string, error) {
+	if outputPath != "" {
+		fi, err := os.Stat(outputPath)
+		if err != nil || !fi.IsDir() {
+			return outputPath, nil
+		}
+	}
+
+	// Имя из метаданных не должно выводить файл за пределы каталога
+	name := filepath.Base(filepath.Clean("/" + meta.Name))
+	if meta.Name == "" || name == "/" || name == "." {
+		return "", errors.New("исходное имя файла неизвестно, укажите путь для сохранения")
+	}
+
+	path := filepath.Join(outputPath, name)
+	if _, err := os.Lstat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("%w: %s, укажите путь для сохранения или --force", apperrors.ErrFileExists, path)
+	}
+	return path, nil
+}
@@ -0,0 +1,92 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// maxReportedProblems ограничивает число путей в сообщении об ошибке.
+const maxReportedProblems = 10
+
+// CheckPermissions проверяет, что каталог хранилища и всё его содержимое
+// принадлежат текущему пользователю и недоступны остальным: в хранилище
+// лежат токен авторизации и зашифрованные файлы. Отсутствующий каталог
+// не считается ошибкой, он будет создан с нужными правами.
+func CheckPermissions(storagePath string) error {
+	var problems []string
+	err := walkStorage(storagePath, func(path string, info fs.FileInfo) error {
+		if problem := checkEntry(info); problem != "" {
+			problems = append(problems, path+": "+problem)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if len(problems) > maxReportedProblems {
+		more := len(problems) - maxReportedProblems
+		problems = append(problems[:maxReportedProblems], fmt.Sprintf("и ещё %d", more))
+	}
+	return fmt.Errorf("%w:\n%s", apperrors.ErrInsecureStorage, strings.Join(problems, "\n"))
+}
+
+// FixPermissions выставляет каталогам хранилища права 0700, а файлам —
+// 0600. Владельца файлов исправить нельзя, поэтому после исправления
+// хранилище нужно проверить ещё раз.
+func FixPermissions(storagePath string) error {
+	return walkStorage(storagePath, func(path string, info fs.FileInfo) error {
+		perm := filePerm
+		if info.IsDir() {
+			perm = dirPerm
+		}
+		if info.Mode().Perm() == perm {
+			return nil
+		}
+		return os.Chmod(path, perm)
+	})
+}
+
+// walkStorage обходит каталог хранилища, пропуская символические ссылки
+// внутри него: их собственные права ни на что не влияют. Сам каталог
+// хранилища может быть ссылкой, тогда обходится каталог, на который она
+// указывает, а пути передаются относительно storagePath.
+func walkStorage(storagePath string, fn func(path string, info fs.FileInfo) error) error {
+	if _, err := os.Stat(storagePath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	root, err := filepath.EvalSymlinks(storagePath)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.Join(storagePath, rel), info)
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filestore
+
+import "io/fs"
+
+// checkEntry ничего не проверяет: в Windows доступ к файлам определяют
+// списки ACL, а биты режима, которые возвращает os, их не отражают.
+func checkEntry(info fs.FileInfo) string {
+	return ""
+}
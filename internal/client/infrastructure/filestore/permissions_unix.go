@@ -0,0 +1,22 @@
+//go:build unix
+
+package filestore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkEntry возвращает описание проблемы с правами или владельцем файла
+// либо пустую строку.
+func checkEntry(info fs.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Sprintf("владелец uid %d, а не текущий пользователь", st.Uid)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Sprintf("права %04o открывают доступ другим пользователям", perm)
+	}
+	return ""
+}
@@ -3,14 +3,21 @@ package card
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/jsonrecord"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 )
 
 type FileRepository interface {
-	EncryptFile(inputPath, outputName, password string) error
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+}
+
+type NameIndex interface {
+	Assign(name string, key *masterkey.Key) (string, error)
 }
 
 type HTTPClient interface {
@@ -29,30 +36,50 @@ type CardJSON struct {
 	Number string `json:"number"`
 	Date   string `json:"date"`
 	CVV    string `json:"cvv"`
+	Brand  string `json:"brand,omitempty"`
+	Holder string `json:"holder,omitempty"`
+	Bank   string `json:"bank,omitempty"`
+	PIN    string `json:"pin,omitempty"`
+	Notes  string `json:"notes,omitempty"`
 }
 
-func Run(Number string, Date string, CVV string, Password string, repo FileRepository, http HTTPClient) (string, error) {
+// Category категория записи карты по умолчанию.
+const Category = "card"
 
-	card := &CardJSON{
-		Number: Number,
-		Date:   Date,
-		CVV:    CVV,
-	}
-	filename := "card_" + strings.ReplaceAll(Number, " ", "") + ".json"
-	path := filepath.Join(os.TempDir(), filename)
+// RecordName возвращает имя записи карты в локальном индексе.
+func RecordName(Number string) string {
+	return "card_" + NormalizeNumber(Number) + ".json"
+}
 
-	cardJSON, err := json.Marshal(card)
-	if err != nil {
+// LegacyRecordName возвращает имя, под которым прежние версии сохраняли
+// карту: из номера удалялись только пробелы, дефисы оставались.
+func LegacyRecordName(Number string) string {
+	return "card_" + strings.ReplaceAll(Number, " ", "") + ".json"
+}
+
+// Run проверяет данные карты, шифрует их в памяти и возвращает
+// идентификатор объекта. Неверные поля возвращаются в apperrors.FieldErrors
+// до шифрования. Открытые данные карты не записываются на диск, а номер
+// карты остаётся только в зашифрованном индексе и метаданных. Если категория
+// не задана, запись относится к категории Category.
+func Run(card CardJSON, Labels entities.Labels, Key *masterkey.Key, repo FileRepository, names NameIndex, http HTTPClient) (string, error) {
+	if Key == nil {
+		return "", errors.New("empty password")
+	}
+	if err := Check(&card); err != nil {
 		return "", err
 	}
-	var permissions os.FileMode = 0644 // Read/write for owner, read-only for others
 
-	err = os.WriteFile(path, cardJSON, permissions)
+	name := RecordName(card.Number)
+	filename, err := names.Assign(name, Key)
 	if err != nil {
 		return "", err
 	}
 
-	err = repo.EncryptFile(path, filename, Password)
+	err = jsonrecord.Write(repo, filename, &card, Key, entities.Metadata{
+		Alias:  name,
+		Labels: Labels.OrCategory(Category),
+	})
 	if err != nil {
 		return "", err
 	}
@@ -1,73 +1,113 @@
 package card
 
 import (
-	"os"
-	"path/filepath"
-	"strings"
+	"encoding/json"
 	"testing"
 
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockFileRepository struct {
 	mock.Mock
 }
 
-func (m *MockFileRepository) EncryptFile(inputPath, outputName, password string) error {
-	args := m.Called(inputPath, outputName, password)
+func (m *MockFileRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	// Данные затираются после вызова, поэтому сохраняем копию
+	args := m.Called(name, string(data), key, meta)
 	return args.Error(0)
 }
 
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Assign(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
 func TestRun(t *testing.T) {
 	mockRepo := new(MockFileRepository)
+	mockIndex := new(MockNameIndex)
+	key := &masterkey.Key{Password: "secure"}
 
 	tests := []struct {
-		name     string
-		number   string
-		date     string
-		cvv      string
-		password string
-		mockErr  error
-		wantErr  bool
+		name    string
+		card    CardJSON
+		mockErr error
+		wantErr bool
 	}{
 		{
-			name:     "success",
-			number:   "1234 5678 9012 3456",
-			date:     "12/25",
-			cvv:      "123",
-			password: "secure",
-			mockErr:  nil,
-			wantErr:  false,
+			name:    "success",
+			card:    CardJSON{Number: "4111 1111 1111 1111", Date: "12/25", CVV: "123"},
+			mockErr: nil,
+			wantErr: false,
 		},
 		{
-			name:     "encryption error",
-			number:   "1234 5678 9012 3456",
-			date:     "12/25",
-			cvv:      "123",
-			password: "secure",
-			mockErr:  assert.AnError,
-			wantErr:  true,
+			name: "optional fields",
+			card: CardJSON{
+				Number: "4111-1111-1111-1111",
+				Date:   "12/25",
+				CVV:    "123",
+				Holder: "IVAN IVANOV",
+				Bank:   "Example Bank",
+				PIN:    "1234",
+				Notes:  "зарплатная",
+			},
+			mockErr: nil,
+			wantErr: false,
+		},
+		{
+			name:    "encryption error",
+			card:    CardJSON{Number: "4111 1111 1111 1111", Date: "12/25", CVV: "123"},
+			mockErr: assert.AnError,
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo.On("EncryptFile", mock.Anything, mock.Anything, tt.password).Return(tt.mockErr).Once()
+			mockIndex.On("Assign", "card_4111111111111111.json", key).Return("5a4b3c", nil).Once()
+			stored := tt.card
+			stored.Number = "4111111111111111"
+			stored.Brand = BrandVisa
+			cardJSON, _ := json.Marshal(&stored)
+			meta := entities.Metadata{Alias: "card_4111111111111111.json", MIMEType: "application/json", Labels: entities.Labels{Category: "card"}}
+			mockRepo.On("WriteRecord", "5a4b3c", string(cardJSON), key, meta).Return(tt.mockErr).Once()
 
-			_, err := Run(tt.number, tt.date, tt.cvv, tt.password, mockRepo, nil)
+			id, err := Run(tt.card, entities.Labels{}, key, mockRepo, mockIndex, nil)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				// Clean up temp file
-				filename := "card_" + strings.ReplaceAll(tt.number, " ", "") + ".json"
-				os.Remove(filepath.Join(os.TempDir(), filename))
+				assert.Equal(t, "5a4b3c", id)
 			}
 
 			mockRepo.AssertExpectations(t)
+			mockIndex.AssertExpectations(t)
 		})
 	}
+
+	t.Run("invalid card", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		index := new(MockNameIndex)
+
+		_, err := Run(CardJSON{Number: "1234 5678 9012 3456", Date: "13/25", CVV: "12"}, entities.Labels{}, key, repo, index, nil)
+		var fields apperrors.FieldErrors
+		require.ErrorAs(t, err, &fields)
+		assert.Len(t, fields, 3)
+		index.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := Run(CardJSON{Number: "4111 1111 1111 1111", Date: "12/25", CVV: "123"}, entities.Labels{}, nil, mockRepo, mockIndex, nil)
+		assert.EqualError(t, err, "empty password")
+	})
 }
 
 func TestExtractUUID(t *testing.T) {
@@ -0,0 +1,158 @@
+package card
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// Платёжные системы, определяемые по первым цифрам номера (IIN).
+const (
+	BrandVisa       = "Visa"
+	BrandMastercard = "Mastercard"
+	BrandMir        = "Mir"
+	BrandAmex       = "American Express"
+)
+
+// Допустимые длины номера карт известных платёжных систем.
+var brandLengths = map[string][]int{
+	BrandVisa:       {13, 16, 19},
+	BrandMastercard: {16},
+	BrandMir:        {16, 17, 18, 19},
+	BrandAmex:       {15},
+}
+
+// NormalizeNumber убирает из номера карты пробелы и дефисы.
+func NormalizeNumber(Number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(Number))
+}
+
+// Brand возвращает платёжную систему по первым цифрам номера или пустую
+// строку, если система не известна.
+func Brand(Number string) string {
+	number := NormalizeNumber(Number)
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		p, err := strconv.Atoi(number[:n])
+		if err != nil {
+			return -1
+		}
+		return p
+	}
+
+	switch {
+	case prefix(1) == 4:
+		return BrandVisa
+	case prefix(2) == 34, prefix(2) == 37:
+		return BrandAmex
+	case prefix(4) >= 2200 && prefix(4) <= 2204:
+		return BrandMir
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return BrandMastercard
+	}
+	return ""
+}
+
+// Luhn проверяет контрольную цифру номера по алгоритму Луна.
+func Luhn(number string) bool {
+	if number == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ParseDate разбирает срок действия в формате MM/YY и возвращает момент,
+// начиная с которого карта недействительна: первый день следующего месяца.
+func ParseDate(Date string) (time.Time, error) {
+	t, err := time.Parse("01/06", strings.TrimSpace(Date))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 1, 0), nil
+}
+
+// Expired сообщает, истёк ли срок действия карты к моменту now. Карта
+// действует до конца указанного месяца.
+func (c *CardJSON) Expired(now time.Time) bool {
+	end, err := ParseDate(c.Date)
+	if err != nil {
+		return false
+	}
+	return !now.UTC().Before(end)
+}
+
+// Check проверяет поля карты, приводит номер к цифрам без разделителей
+// и заполняет платёжную систему. Ошибки всех неверных полей возвращаются
+// вместе в apperrors.FieldErrors с ключами number, date, cvv и pin.
+// Истёкший срок действия ошибкой не считается, его проверяет Expired.
+func Check(c *CardJSON) error {
+	errs := apperrors.FieldErrors{}
+
+	c.Number = NormalizeNumber(c.Number)
+	c.Date = strings.TrimSpace(c.Date)
+	c.Brand = Brand(c.Number)
+
+	switch {
+	case c.Number == "":
+		errs["number"] = "укажите номер карты"
+	case !digits(c.Number):
+		errs["number"] = "номер должен состоять из цифр"
+	case len(c.Number) < 12 || len(c.Number) > 19:
+		errs["number"] = "номер должен содержать от 12 до 19 цифр"
+	case c.Brand != "" && !slices.Contains(brandLengths[c.Brand], len(c.Number)):
+		errs["number"] = "неверная длина номера " + c.Brand
+	case !Luhn(c.Number):
+		errs["number"] = "неверная контрольная цифра номера"
+	}
+
+	if _, err := ParseDate(c.Date); err != nil {
+		errs["date"] = "срок действия в формате MM/YY"
+	}
+
+	cvvLength := 3
+	if c.Brand == BrandAmex {
+		cvvLength = 4
+	}
+	if len(c.CVV) != cvvLength || !digits(c.CVV) {
+		errs["cvv"] = "CVV должен содержать " + strconv.Itoa(cvvLength) + " цифры"
+	}
+
+	if c.PIN != "" && (len(c.PIN) < 4 || len(c.PIN) > 12 || !digits(c.PIN)) {
+		errs["pin"] = "PIN должен содержать от 4 до 12 цифр"
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
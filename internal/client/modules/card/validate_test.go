@@ -0,0 +1,100 @@
+package card
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrand(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"4111 1111 1111 1111", BrandVisa},
+		{"5500 0000 0000 0004", BrandMastercard},
+		{"2221 0000 0000 0009", BrandMastercard},
+		{"2720 9900 0000 0007", BrandMastercard},
+		{"2200 0000 0000 0004", BrandMir},
+		{"2204 9999 9999 9990", BrandMir},
+		{"3782 822463 10005", BrandAmex},
+		{"3400 000000 00009", BrandAmex},
+		{"2205 0000 0000 0000", ""},
+		{"6011 1111 1111 1117", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			assert.Equal(t, tt.want, Brand(tt.number))
+		})
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	assert.True(t, Luhn("4111111111111111"))
+	assert.True(t, Luhn("378282246310005"))
+	assert.True(t, Luhn("2200000000000004"))
+	assert.False(t, Luhn("4111111111111112"))
+	assert.False(t, Luhn("4111a11111111111"))
+	assert.False(t, Luhn(""))
+}
+
+func TestExpired(t *testing.T) {
+	card := &CardJSON{Date: "02/24"}
+	assert.False(t, card.Expired(time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC)))
+	assert.True(t, card.Expired(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Срок в конце года переходит на январь следующего
+	card = &CardJSON{Date: "12/25"}
+	assert.False(t, card.Expired(time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC)))
+	assert.True(t, card.Expired(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	card = &CardJSON{Date: "invalid"}
+	assert.False(t, card.Expired(time.Now()))
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		card := &CardJSON{Number: " 3782-822463-10005 ", Date: " 01/30", CVV: "1234", PIN: "0000"}
+		require.NoError(t, Check(card))
+		assert.Equal(t, "378282246310005", card.Number)
+		assert.Equal(t, "01/30", card.Date)
+		assert.Equal(t, BrandAmex, card.Brand)
+	})
+
+	t.Run("unknown brand", func(t *testing.T) {
+		card := &CardJSON{Number: "6011 1111 1111 1117", Date: "01/30", CVV: "123"}
+		require.NoError(t, Check(card))
+		assert.Empty(t, card.Brand)
+	})
+
+	tests := []struct {
+		name  string
+		card  CardJSON
+		field string
+	}{
+		{"empty number", CardJSON{Date: "01/30", CVV: "123"}, "number"},
+		{"letters in number", CardJSON{Number: "4111 1111 1111 111a", Date: "01/30", CVV: "123"}, "number"},
+		{"short number", CardJSON{Number: "4111 1111 11", Date: "01/30", CVV: "123"}, "number"},
+		{"brand length", CardJSON{Number: "5500 0000 0000 0000 04", Date: "01/30", CVV: "123"}, "number"},
+		{"luhn", CardJSON{Number: "4111 1111 1111 1112", Date: "01/30", CVV: "123"}, "number"},
+		{"month", CardJSON{Number: "4111 1111 1111 1111", Date: "13/30", CVV: "123"}, "date"},
+		{"date format", CardJSON{Number: "4111 1111 1111 1111", Date: "01/2030", CVV: "123"}, "date"},
+		{"empty date", CardJSON{Number: "4111 1111 1111 1111", CVV: "123"}, "date"},
+		{"cvv", CardJSON{Number: "4111 1111 1111 1111", Date: "01/30", CVV: "1234"}, "cvv"},
+		{"amex cvv", CardJSON{Number: "3782 822463 10005", Date: "01/30", CVV: "123"}, "cvv"},
+		{"pin", CardJSON{Number: "4111 1111 1111 1111", Date: "01/30", CVV: "123", PIN: "12"}, "pin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(&tt.card)
+			var fields apperrors.FieldErrors
+			require.ErrorAs(t, err, &fields)
+			assert.Len(t, fields, 1)
+			assert.Contains(t, fields, tt.field)
+		})
+	}
+}
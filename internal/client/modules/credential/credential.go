@@ -0,0 +1,187 @@
+// Package credential хранит учётные данные сайтов и сервисов: адрес, логин,
+// пароль и заметки. Запись шифруется в памяти и хранится так же, как карта.
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/jsonrecord"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Category категория записи учётных данных по умолчанию.
+const Category = "credential"
+
+// recordPrefix отличает учётные данные от других записей индекса.
+const recordPrefix = "cred_"
+
+type FileRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+	ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error)
+}
+
+type NameIndex interface {
+	Assign(name string, key *masterkey.Key) (string, error)
+	Resolve(name string, key *masterkey.Key) (string, error)
+	List(key *masterkey.Key) (map[string]string, error)
+}
+
+type CredentialJSON struct {
+	URL      string `json:"url,omitempty"`
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Notes    string `json:"notes,omitempty"`
+}
+
+// Changes изменения записи учётных данных. Поле nil не меняется, а пустая
+// строка очищает поле.
+type Changes struct {
+	URL      *string
+	Login    *string
+	Password *string
+	Notes    *string
+}
+
+// RecordName возвращает имя записи учётных данных в локальном индексе.
+func RecordName(Name string) string {
+	return recordPrefix + Name
+}
+
+// Add создаёт запись учётных данных и возвращает идентификатор объекта.
+// Существующая запись не перезаписывается, для изменения служит Update.
+func Add(Name string, cred CredentialJSON, Labels entities.Labels, Key *masterkey.Key, repo FileRepository, names NameIndex) (string, error) {
+	if Key == nil {
+		return "", errors.New("empty password")
+	}
+	if Name == "" {
+		return "", errors.New("empty credential name")
+	}
+
+	name := RecordName(Name)
+	_, err := names.Resolve(name, Key)
+	if err == nil {
+		return "", fmt.Errorf("запись %s уже существует, для изменения используйте cred update", Name)
+	}
+	if !errors.Is(err, apperrors.ErrFileNotFound) {
+		return "", err
+	}
+
+	filename, err := names.Assign(name, Key)
+	if err != nil {
+		return "", err
+	}
+	meta := entities.Metadata{Labels: Labels.OrCategory(Category)}
+	if err := write(filename, name, &cred, meta, Key, repo); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// Get находит запись учётных данных по имени и расшифровывает её в памяти.
+func Get(Name string, Key *masterkey.Key, repo FileRepository, names NameIndex) (*CredentialJSON, error) {
+	_, cred, err := read(Name, Key, repo, names)
+	return cred, err
+}
+
+// Update заменяет заданные в changes поля и заданные метки в существующей
+// записи и возвращает идентификатор объекта. Время создания записи
+// сохраняется.
+func Update(Name string, changes Changes, Labels entities.Labels, Key *masterkey.Key, repo FileRepository, names NameIndex) (string, error) {
+	filename, cred, err := read(Name, Key, repo, names)
+	if err != nil {
+		return "", err
+	}
+	meta, err := repo.ReadMetadata(filename, Key)
+	if err != nil {
+		return "", err
+	}
+
+	for _, field := range []struct {
+		value  *string
+		change *string
+	}{
+		{&cred.URL, changes.URL},
+		{&cred.Login, changes.Login},
+		{&cred.Password, changes.Password},
+		{&cred.Notes, changes.Notes},
+	} {
+		if field.change != nil {
+			*field.value = *field.change
+		}
+	}
+
+	meta.Labels = meta.Labels.OrCategory(Category).Merge(Labels)
+	if err := write(filename, RecordName(Name), cred, *meta, Key, repo); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// List возвращает отсортированные имена записей учётных данных.
+func List(Key *masterkey.Key, names NameIndex) ([]string, error) {
+	if Key == nil {
+		return nil, errors.New("empty password")
+	}
+	entries, err := names.List(Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []string
+	for name := range entries {
+		if n, ok := strings.CutPrefix(name, recordPrefix); ok {
+			list = append(list, n)
+		}
+	}
+	slices.Sort(list)
+	return list, nil
+}
+
+// Format возвращает запись учётных данных в виде для вывода на экран.
+func Format(cred *CredentialJSON) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "Адрес:", cred.URL)
+	fmt.Fprintln(&b, "Логин:", cred.Login)
+	fmt.Fprintln(&b, "Пароль:", cred.Password)
+	if cred.Notes != "" {
+		fmt.Fprintln(&b, "Заметки:", cred.Notes)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func read(Name string, Key *masterkey.Key, repo FileRepository, names NameIndex) (string, *CredentialJSON, error) {
+	if Key == nil {
+		return "", nil, errors.New("empty password")
+	}
+	if Name == "" {
+		return "", nil, errors.New("empty credential name")
+	}
+
+	filename, err := names.Resolve(RecordName(Name), Key)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := repo.ReadRecord(filename, Key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer clear(data)
+
+	var cred CredentialJSON
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal credential: %v", err)
+	}
+	return filename, &cred, nil
+}
+
+func write(filename, name string, cred *CredentialJSON, meta entities.Metadata, Key *masterkey.Key, repo FileRepository) error {
+	meta.Alias = name
+	return jsonrecord.Write(repo, filename, cred, Key, meta)
+}
@@ -0,0 +1,208 @@
+package credential
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	// Данные затираются после вызова, поэтому сохраняем копию
+	args := m.Called(name, string(data), key, meta)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	args := m.Called(name, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	// Возвращаем копию: вызывающий код затирает данные
+	return []byte(args.String(0)), args.Error(1)
+}
+
+func (m *MockFileRepository) ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error) {
+	args := m.Called(filename, key)
+	meta, _ := args.Get(0).(*entities.Metadata)
+	return meta, args.Error(1)
+}
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Assign(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNameIndex) List(key *masterkey.Key) (map[string]string, error) {
+	args := m.Called(key)
+	entries, _ := args.Get(0).(map[string]string)
+	return entries, args.Error(1)
+}
+
+func marshal(t *testing.T, cred CredentialJSON) string {
+	data, err := json.Marshal(cred)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestAdd(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+	cred := CredentialJSON{URL: "https://example.com", Login: "alice", Password: "s3cret"}
+	meta := entities.Metadata{Alias: "cred_example", MIMEType: "application/json", Labels: entities.Labels{Category: Category}}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "cred_example", key).Return("", apperrors.ErrFileNotFound)
+		names.On("Assign", "cred_example", key).Return("5a4b3c", nil)
+		repo.On("WriteRecord", "5a4b3c", marshal(t, cred), key, meta).Return(nil)
+
+		id, err := Add("example", cred, entities.Labels{}, key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, "5a4b3c", id)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("existing record is not overwritten", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "cred_example", key).Return("5a4b3c", nil)
+
+		_, err := Add("example", cred, entities.Labels{}, key, repo, names)
+		assert.ErrorContains(t, err, "cred update")
+		names.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "WriteRecord", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		_, err := Add("", cred, entities.Labels{}, key, new(MockFileRepository), new(MockNameIndex))
+		assert.Error(t, err)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := Add("example", cred, entities.Labels{}, nil, new(MockFileRepository), new(MockNameIndex))
+		assert.Error(t, err)
+	})
+}
+
+func TestGet(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+	cred := CredentialJSON{URL: "https://example.com", Login: "alice", Password: "s3cret", Notes: "2FA"}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "cred_example", key).Return("5a4b3c", nil)
+		repo.On("ReadRecord", "5a4b3c", key).Return(marshal(t, cred), nil)
+
+		got, err := Get("example", key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, cred, *got)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		names := new(MockNameIndex)
+		names.On("Resolve", "cred_missing", key).Return("", apperrors.ErrFileNotFound)
+
+		_, err := Get("missing", key, new(MockFileRepository), names)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "cred_example", key).Return("5a4b3c", nil)
+		repo.On("ReadRecord", "5a4b3c", key).Return(nil, apperrors.ErrWrongPassword)
+
+		_, err := Get("example", key, repo, names)
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stored := CredentialJSON{URL: "https://example.com", Login: "alice", Password: "old", Notes: "2FA"}
+
+	setup := func(updated CredentialJSON, labels entities.Labels) *MockFileRepository {
+		repo := new(MockFileRepository)
+		repo.On("ReadRecord", "5a4b3c", key).Return(marshal(t, stored), nil)
+		repo.On("ReadMetadata", "5a4b3c", key).Return(&entities.Metadata{
+			Alias:   "cred_example",
+			Created: created,
+			Labels:  entities.Labels{Description: "почта", Category: Category, Tags: []string{"personal"}},
+		}, nil)
+		meta := entities.Metadata{
+			Alias:    "cred_example",
+			MIMEType: "application/json",
+			Created:  created,
+			Labels:   labels,
+		}
+		repo.On("WriteRecord", "5a4b3c", marshal(t, updated), key, meta).Return(nil)
+		return repo
+	}
+	names := new(MockNameIndex)
+	names.On("Resolve", "cred_example", key).Return("5a4b3c", nil)
+
+	t.Run("changed fields", func(t *testing.T) {
+		updated := CredentialJSON{URL: "https://example.com", Login: "alice", Password: "new", Notes: "2FA"}
+		repo := setup(updated, entities.Labels{Description: "почта", Category: "work", Tags: []string{"personal"}})
+
+		// Незаданные поля и метки изменений сохраняют прежние значения
+		secret := "new"
+		id, err := Update("example", Changes{Password: &secret}, entities.Labels{Category: "work"}, key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, "5a4b3c", id)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("cleared fields", func(t *testing.T) {
+		updated := CredentialJSON{Login: "alice", Password: "old"}
+		repo := setup(updated, entities.Labels{Description: "почта", Category: Category, Tags: []string{"personal"}})
+
+		empty := ""
+		_, err := Update("example", Changes{URL: &empty, Notes: &empty}, entities.Labels{}, key, repo, names)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestList(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+	names := new(MockNameIndex)
+	names.On("List", key).Return(map[string]string{
+		"cred_mail":                  "1",
+		"card_1234567890123456.json": "2",
+		"cred_bank":                  "3",
+		"report.pdf":                 "4",
+	}, nil)
+
+	list, err := List(key, names)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bank", "mail"}, list)
+}
+
+func TestFormat(t *testing.T) {
+	text := Format(&CredentialJSON{URL: "https://example.com", Login: "alice", Password: "s3cret"})
+	assert.Equal(t, "Адрес: https://example.com\nЛогин: alice\nПароль: s3cret", text)
+	assert.Contains(t, Format(&CredentialJSON{Notes: "2FA"}), "Заметки: 2FA")
+}
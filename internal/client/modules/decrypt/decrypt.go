@@ -8,6 +8,7 @@ import (
 	"io"
 
 	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 )
 
 type FileRepository interface {
@@ -15,7 +16,13 @@ type FileRepository interface {
 	FindAll(ctx context.Context) (*entities.Files, error)
 	Delete(ctx context.Context, uuid string) error
 	GetFileContent(ctx context.Context, uuid string) (string, error)
-	DecryptFile(inputName, outputPath, password string) error
+	DecryptFile(inputName, outputPath string, key *masterkey.Key) (string, error)
+	DecryptStream(src io.Reader, outputPath string, key *masterkey.Key) (string, error)
+	Exists(filename string) bool
+}
+
+type NameIndex interface {
+	Resolve(name string, key *masterkey.Key) (string, error)
 }
 
 type HTTPClient interface {
@@ -23,32 +30,63 @@ type HTTPClient interface {
 	Get(endpoint string, queryParams map[string]string) ([]byte, error)
 	Post(endpoint string, body any) ([]byte, error)
 	DownloadFile(fileURL, outputPath string) error
+	OpenFile(fileURL string) (io.ReadCloser, error)
 }
 
 type LoginResponse struct {
 	Token string `json:"token"`
 }
 
-func Run(password string, inputName string, outputPath string, repo FileRepository) error {
+// Run расшифровывает файл. Если outputPath пуст или указывает на каталог,
+// файл сохраняется под исходным именем из зашифрованных метаданных.
+// Путь "-" выводит данные в стандартный вывод. Файл, которого нет
+// в хранилище, расшифровывается по мере скачивания с сервера без
+// сохранения локальной копии.
+func Run(key *masterkey.Key, inputName string, outputPath string, repo FileRepository, names NameIndex, http HTTPClient) error {
 
-	if password == "" {
+	if key == nil {
 		return errors.New("empty password")
 	}
 	if inputName == "" {
 		return errors.New("empty input file name")
 	}
-	if outputPath == "" {
-		return errors.New("empty output file path")
+
+	id, err := names.Resolve(inputName, key)
+	if err != nil {
+		return err
 	}
 
-	err := repo.DecryptFile(inputName, outputPath, password)
+	var path string
+	if repo.Exists(id) {
+		path, err = repo.DecryptFile(id, outputPath, key)
+	} else {
+		path, err = decryptRemote(id, outputPath, key, repo, http)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Стандартный вывод занят расшифрованными данными
+	if path != stdio {
+		fmt.Println("Файл расшифрован:", path)
+	}
+
 	return nil
 }
 
+// stdio путь стандартного вывода.
+const stdio = "-"
+
+func decryptRemote(id, outputPath string, key *masterkey.Key, repo FileRepository, http HTTPClient) (string, error) {
+	body, err := http.OpenFile("/file?name=" + id)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	return repo.DecryptStream(body, outputPath, key)
+}
+
 func ExtractToken(responseBytes []byte) (string, error) {
 	var tokenResp LoginResponse
 	if err := json.Unmarshal(responseBytes, &tokenResp); err != nil {
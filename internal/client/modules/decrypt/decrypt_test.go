@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -15,9 +17,19 @@ type MockFileRepository struct {
 	mock.Mock
 }
 
-func (m *MockFileRepository) DecryptFile(inputName, outputPath, password string) error {
-	args := m.Called(inputName, outputPath, password)
-	return args.Error(0)
+func (m *MockFileRepository) DecryptFile(inputName, outputPath string, key *masterkey.Key) (string, error) {
+	args := m.Called(inputName, outputPath, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFileRepository) DecryptStream(src io.Reader, outputPath string, key *masterkey.Key) (string, error) {
+	args := m.Called(src, outputPath, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFileRepository) Exists(filename string) bool {
+	args := m.Called(filename)
+	return args.Bool(0)
 }
 
 func (m *MockFileRepository) Save(ctx context.Context, filename string, data io.Reader) error {
@@ -36,12 +48,48 @@ func (m *MockFileRepository) GetFileContent(ctx context.Context, uuid string) (s
 	return "", nil
 }
 
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+type MockHTTPClient struct {
+	mock.Mock
+}
+
+func (m *MockHTTPClient) SetHeader(key, value string) {}
+
+func (m *MockHTTPClient) Get(endpoint string, queryParams map[string]string) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *MockHTTPClient) Post(endpoint string, body any) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *MockHTTPClient) DownloadFile(fileURL, outputPath string) error {
+	return nil
+}
+
+func (m *MockHTTPClient) OpenFile(fileURL string) (io.ReadCloser, error) {
+	args := m.Called(fileURL)
+	body, _ := args.Get(0).(io.ReadCloser)
+	return body, args.Error(1)
+}
+
+var testKey = &masterkey.Key{Password: "pass", Auth: "auth", Vault: []byte("vault")}
+
 func TestRun(t *testing.T) {
 	mockRepo := new(MockFileRepository)
+	mockIndex := new(MockNameIndex)
 
 	tests := []struct {
 		name      string
-		password  string
+		key       *masterkey.Key
 		inputName string
 		output    string
 		mockErr   error
@@ -50,15 +98,31 @@ func TestRun(t *testing.T) {
 	}{
 		{
 			name:      "success",
-			password:  "pass",
+			key:       testKey,
 			inputName: "input",
 			output:    "output",
 			mockErr:   nil,
 			wantErr:   false,
 		},
+		{
+			name:      "original name",
+			key:       testKey,
+			inputName: "input",
+			output:    "",
+			mockErr:   nil,
+			wantErr:   false,
+		},
+		{
+			name:      "stdout",
+			key:       testKey,
+			inputName: "input",
+			output:    "-",
+			mockErr:   nil,
+			wantErr:   false,
+		},
 		{
 			name:      "empty password",
-			password:  "",
+			key:       nil,
 			inputName: "input",
 			output:    "output",
 			wantErr:   true,
@@ -66,7 +130,7 @@ func TestRun(t *testing.T) {
 		},
 		{
 			name:      "decryption error",
-			password:  "pass",
+			key:       testKey,
 			inputName: "input",
 			output:    "output",
 			mockErr:   errors.New("decryption failed"),
@@ -76,11 +140,13 @@ func TestRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.password != "" {
-				mockRepo.On("DecryptFile", tt.inputName, tt.output, tt.password).Return(tt.mockErr).Once()
+			if tt.key != nil {
+				mockIndex.On("Resolve", tt.inputName, tt.key).Return("9a8b7c", nil).Once()
+				mockRepo.On("Exists", "9a8b7c").Return(true).Once()
+				mockRepo.On("DecryptFile", "9a8b7c", tt.output, tt.key).Return("output", tt.mockErr).Once()
 			}
 
-			err := Run(tt.password, tt.inputName, tt.output, mockRepo)
+			err := Run(tt.key, tt.inputName, tt.output, mockRepo, mockIndex, nil)
 			if tt.wantErr {
 				assert.Error(t, err)
 				if tt.errMsg != "" {
@@ -91,6 +157,42 @@ func TestRun(t *testing.T) {
 			}
 
 			mockRepo.AssertExpectations(t)
+			mockIndex.AssertExpectations(t)
 		})
 	}
 }
+
+func TestRunRemote(t *testing.T) {
+	t.Run("streams from server", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockHTTP := new(MockHTTPClient)
+
+		body := io.NopCloser(strings.NewReader("ciphertext"))
+		mockIndex.On("Resolve", "input", testKey).Return("9a8b7c", nil).Once()
+		mockRepo.On("Exists", "9a8b7c").Return(false).Once()
+		mockHTTP.On("OpenFile", "/file?name=9a8b7c").Return(body, nil).Once()
+		mockRepo.On("DecryptStream", body, "-", testKey).Return("-", nil).Once()
+
+		err := Run(testKey, "input", "-", mockRepo, mockIndex, mockHTTP)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+		mockIndex.AssertExpectations(t)
+		mockHTTP.AssertExpectations(t)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockHTTP := new(MockHTTPClient)
+
+		mockIndex.On("Resolve", "input", testKey).Return("9a8b7c", nil).Once()
+		mockRepo.On("Exists", "9a8b7c").Return(false).Once()
+		mockHTTP.On("OpenFile", "/file?name=9a8b7c").Return(nil, errors.New("unexpected status code: 404")).Once()
+
+		err := Run(testKey, "input", "out", mockRepo, mockIndex, mockHTTP)
+		assert.EqualError(t, err, "unexpected status code: 404")
+		mockRepo.AssertNotCalled(t, "DecryptStream", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -2,13 +2,20 @@ package download
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 )
 
 type FileRepository interface {
 	GetPath(filename string) string
 }
 
+type NameIndex interface {
+	Resolve(name string, key *masterkey.Key) (string, error)
+}
+
 type HTTPClient interface {
 	DownloadFile(fileURL, outputPath string) error
 }
@@ -17,13 +24,24 @@ type UploadResponse struct {
 	UUID string `json:"uuid"`
 }
 
-func Run(inputName string, repo FileRepository, http HTTPClient) error {
+// Run скачивает с сервера объект записи inputName. Сервер знает объект
+// только по идентификатору, поэтому имя ищется в локальном индексе.
+func Run(key *masterkey.Key, inputName string, repo FileRepository, names NameIndex, http HTTPClient) error {
 	// ctx := context.Background()
 
-	url := "/file?name=" + inputName
-	filepath := repo.GetPath(inputName)
+	if key == nil {
+		return errors.New("empty password")
+	}
+
+	id, err := names.Resolve(inputName, key)
+	if err != nil {
+		return err
+	}
+
+	url := "/file?name=" + id
+	filepath := repo.GetPath(id)
 
-	err := http.DownloadFile(url, filepath)
+	err = http.DownloadFile(url, filepath)
 	if err != nil {
 		return err
 	}
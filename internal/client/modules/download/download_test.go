@@ -3,6 +3,7 @@ package download
 import (
 	"testing"
 
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -16,6 +17,15 @@ func (m *MockFileRepository) GetPath(filename string) string {
 	return args.String(0)
 }
 
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
 type MockHTTPClient struct {
 	mock.Mock
 }
@@ -28,6 +38,8 @@ func (m *MockHTTPClient) DownloadFile(fileURL, outputPath string) error {
 func TestRun(t *testing.T) {
 	mockRepo := new(MockFileRepository)
 	mockHTTP := new(MockHTTPClient)
+	mockIndex := new(MockNameIndex)
+	key := &masterkey.Key{Password: "pass", Vault: []byte("vault")}
 
 	tests := []struct {
 		name    string
@@ -54,10 +66,11 @@ func TestRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo.On("GetPath", tt.input).Return(tt.path).Once()
-			mockHTTP.On("DownloadFile", "/file?name="+tt.input, tt.path).Return(tt.httpErr).Once()
+			mockIndex.On("Resolve", tt.input, key).Return("3c2b1a", nil).Once()
+			mockRepo.On("GetPath", "3c2b1a").Return(tt.path).Once()
+			mockHTTP.On("DownloadFile", "/file?name=3c2b1a", tt.path).Return(tt.httpErr).Once()
 
-			err := Run(tt.input, mockRepo, mockHTTP)
+			err := Run(key, tt.input, mockRepo, mockIndex, mockHTTP)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -66,6 +79,12 @@ func TestRun(t *testing.T) {
 
 			mockRepo.AssertExpectations(t)
 			mockHTTP.AssertExpectations(t)
+			mockIndex.AssertExpectations(t)
 		})
 	}
+
+	t.Run("empty password", func(t *testing.T) {
+		err := Run(nil, "test.txt", mockRepo, mockIndex, mockHTTP)
+		assert.EqualError(t, err, "empty password")
+	})
 }
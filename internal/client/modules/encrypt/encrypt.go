@@ -4,33 +4,47 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 )
 
 type FileRepository interface {
-	EncryptFile(inputPath, outputName, password string) error
+	EncryptFile(inputPath, outputName string, key *masterkey.Key, meta entities.Metadata) error
+}
+
+type NameIndex interface {
+	Assign(name string, key *masterkey.Key) (string, error)
 }
 
 type LoginResponse struct {
 	Token string `json:"token"`
 }
 
-func Run(password string, inputPath string, outputName string, repo FileRepository) error {
-	if password == "" {
-		return errors.New("empty password")
+// Run шифрует файл в хранилище как запись outputName и возвращает
+// идентификатор объекта, под которым запись хранится и отправляется на сервер.
+func Run(key *masterkey.Key, inputPath string, outputName string, labels entities.Labels, repo FileRepository, names NameIndex) (string, error) {
+	if key == nil {
+		return "", errors.New("empty password")
 	}
 	if inputPath == "" {
-		return errors.New("empty input file path")
+		return "", errors.New("empty input file path")
 	}
 	if outputName == "" {
-		return errors.New("empty output file name")
+		return "", errors.New("empty output file name")
+	}
+
+	id, err := names.Assign(outputName, key)
+	if err != nil {
+		return "", err
 	}
 
-	err := repo.EncryptFile(inputPath, outputName, password)
+	err = repo.EncryptFile(inputPath, id, key, entities.Metadata{Alias: outputName, Labels: labels})
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return id, nil
 }
 
 func ExtractToken(responseBytes []byte) (string, error) {
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -12,17 +14,29 @@ type MockFileRepository struct {
 	mock.Mock
 }
 
-func (m *MockFileRepository) EncryptFile(inputPath, outputName, password string) error {
-	args := m.Called(inputPath, outputName, password)
+func (m *MockFileRepository) EncryptFile(inputPath, outputName string, key *masterkey.Key, meta entities.Metadata) error {
+	args := m.Called(inputPath, outputName, key, meta)
 	return args.Error(0)
 }
 
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Assign(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+var testKey = &masterkey.Key{Password: "pass", Auth: "auth", Vault: []byte("vault")}
+
 func TestRun(t *testing.T) {
 	mockRepo := new(MockFileRepository)
+	mockIndex := new(MockNameIndex)
 
 	tests := []struct {
 		name      string
-		password  string
+		key       *masterkey.Key
 		inputPath string
 		output    string
 		mockErr   error
@@ -31,7 +45,7 @@ func TestRun(t *testing.T) {
 	}{
 		{
 			name:      "success",
-			password:  "pass",
+			key:       testKey,
 			inputPath: "input.txt",
 			output:    "output.enc",
 			mockErr:   nil,
@@ -39,7 +53,7 @@ func TestRun(t *testing.T) {
 		},
 		{
 			name:      "empty password",
-			password:  "",
+			key:       nil,
 			inputPath: "input.txt",
 			output:    "output.enc",
 			wantErr:   true,
@@ -47,7 +61,7 @@ func TestRun(t *testing.T) {
 		},
 		{
 			name:      "encryption error",
-			password:  "pass",
+			key:       testKey,
 			inputPath: "input.txt",
 			output:    "output.enc",
 			mockErr:   errors.New("encryption failed"),
@@ -57,11 +71,13 @@ func TestRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.password != "" {
-				mockRepo.On("EncryptFile", tt.inputPath, tt.output, tt.password).Return(tt.mockErr).Once()
+			if tt.key != nil {
+				mockIndex.On("Assign", tt.output, tt.key).Return("0f1e2d", nil).Once()
+				// Объект получает идентификатор, имя записи хранится в метаданных
+				mockRepo.On("EncryptFile", tt.inputPath, "0f1e2d", tt.key, entities.Metadata{Alias: tt.output}).Return(tt.mockErr).Once()
 			}
 
-			err := Run(tt.password, tt.inputPath, tt.output, mockRepo)
+			id, err := Run(tt.key, tt.inputPath, tt.output, entities.Labels{}, mockRepo, mockIndex)
 			if tt.wantErr {
 				assert.Error(t, err)
 				if tt.errMsg != "" {
@@ -69,9 +85,11 @@ func TestRun(t *testing.T) {
 				}
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, "0f1e2d", id)
 			}
 
 			mockRepo.AssertExpectations(t)
+			mockIndex.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,229 @@
+// Package index сопоставляет имена записей, которые видит пользователь,
+// со случайными идентификаторами объектов в хранилище и на сервере.
+//
+// Соответствие хранится локально в зашифрованном виде. Имя записи также
+// записывается в зашифрованные метаданные каждого файла, поэтому индекс,
+// который отсутствует, устарел после синхронизации или зашифрован прежним
+// паролем, восстанавливается из файлов хранилища.
+package index
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// idSize размер идентификатора объекта в байтах.
+const idSize = 16
+
+type FileRepository interface {
+	FindAll(ctx context.Context) (*entities.Files, error)
+	ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error)
+}
+
+type IndexRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+	Delete(ctx context.Context, filename string) error
+}
+
+// Index индекс имён записей одного пользователя.
+type Index struct {
+	username  string
+	filesRepo FileRepository
+	indexRepo IndexRepository
+}
+
+func New(username string, filesRepo FileRepository, indexRepo IndexRepository) *Index {
+	return &Index{
+		username:  username,
+		filesRepo: filesRepo,
+		indexRepo: indexRepo,
+	}
+}
+
+// NewID возвращает новый случайный идентификатор объекта.
+func NewID() (string, error) {
+	id := make([]byte, idSize)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", fmt.Errorf("ошибка генерации идентификатора: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Assign возвращает идентификатор объекта для записи name. Для новой записи
+// создаётся случайный идентификатор, существующая запись сохраняет прежний.
+func (ix *Index) Assign(name string, key *masterkey.Key) (string, error) {
+	if name == "" {
+		return "", errors.New("empty record name")
+	}
+
+	entries, err := ix.load(key)
+	if err != nil {
+		return "", err
+	}
+	if id, ok := entries[name]; ok {
+		return id, nil
+	}
+
+	id, err := NewID()
+	if err != nil {
+		return "", err
+	}
+	entries[name] = id
+
+	return id, ix.save(entries, key)
+}
+
+// Resolve возвращает идентификатор объекта записи name. Если записи нет
+// в индексе, индекс восстанавливается из файлов хранилища.
+func (ix *Index) Resolve(name string, key *masterkey.Key) (string, error) {
+	if name == "" {
+		return "", errors.New("empty record name")
+	}
+
+	entries, err := ix.load(key)
+	if err != nil {
+		return "", err
+	}
+	if id, ok := entries[name]; ok {
+		return id, nil
+	}
+
+	if entries, err = ix.rebuild(key); err != nil {
+		return "", err
+	}
+	if id, ok := entries[name]; ok {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", apperrors.ErrFileNotFound, name)
+}
+
+// List возвращает все имена записей с идентификаторами их объектов.
+func (ix *Index) List(key *masterkey.Key) (map[string]string, error) {
+	return ix.load(key)
+}
+
+// Invalidate удаляет локальный индекс, например после синхронизации, и он
+// будет восстановлен из файлов при следующем обращении. Ключ не требуется.
+func (ix *Index) Invalidate() error {
+	err := ix.indexRepo.Delete(context.Background(), ix.username)
+	if err != nil && !errors.Is(err, apperrors.ErrFileNotFound) {
+		return err
+	}
+	return nil
+}
+
+// load читает индекс. Отсутствующий индекс или индекс, зашифрованный
+// прежним паролем, восстанавливается из файлов хранилища.
+func (ix *Index) load(key *masterkey.Key) (map[string]string, error) {
+	data, err := ix.indexRepo.ReadRecord(ix.username, key)
+	if errors.Is(err, apperrors.ErrFileNotFound) || errors.Is(err, apperrors.ErrDecryptFailed) {
+		return ix.rebuild(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]string)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index: %v", err)
+	}
+	return entries, nil
+}
+
+// Record запись хранилища с идентификатором объекта и метаданными. У файлов
+// прежних версий клиента и повреждённых файлов метаданных нет.
+type Record struct {
+	Name string
+	ID   string
+	Meta *entities.Metadata
+}
+
+// Records читает метаданные всех файлов хранилища и возвращает по одной
+// записи на имя. Индекс обновляется по прочитанным метаданным.
+func (ix *Index) Records(key *masterkey.Key) ([]Record, error) {
+	records, err := ix.scan(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.save(names(records), key); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// rebuild собирает индекс из метаданных файлов хранилища и сохраняет его.
+func (ix *Index) rebuild(key *masterkey.Key) (map[string]string, error) {
+	records, err := ix.Records(key)
+	if err != nil {
+		return nil, err
+	}
+	return names(records), nil
+}
+
+// scan читает метаданные файлов хранилища. Файлы без имени записи, например
+// зашифрованные прежними версиями клиента, доступны под собственным именем.
+func (ix *Index) scan(key *masterkey.Key) ([]Record, error) {
+	files, err := ix.filesRepo.FindAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	byName := make(map[string]int)
+	for _, file := range *files {
+		record := Record{Name: file.Name, ID: file.Name}
+		meta, err := ix.filesRepo.ReadMetadata(file.Name, key)
+		switch {
+		case errors.Is(err, apperrors.ErrWrongPassword):
+			// Файлы хранилища зашифрованы одним ключом
+			return nil, err
+		case err == nil:
+			record.Meta = meta
+			if meta.Alias != "" {
+				record.Name = meta.Alias
+			}
+		}
+
+		i, ok := byName[record.Name]
+		if !ok {
+			byName[record.Name] = len(records)
+			records = append(records, record)
+			continue
+		}
+		// Одно имя могло быть записано на разных устройствах, берём новую запись
+		prev := records[i].Meta
+		if prev != nil && meta != nil && meta.Created.Before(prev.Created) {
+			continue
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// names возвращает соответствие имён записей идентификаторам объектов.
+func names(records []Record) map[string]string {
+	entries := make(map[string]string, len(records))
+	for _, r := range records {
+		entries[r.Name] = r.ID
+	}
+	return entries
+}
+
+func (ix *Index) save(entries map[string]string, key *masterkey.Key) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return ix.indexRepo.WriteRecord(ix.username, data, key, entities.Metadata{})
+}
@@ -0,0 +1,222 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryFileRepository хранит метаданные файлов в памяти. Файл без
+// метаданных имитирует файл прежнего формата.
+type memoryFileRepository struct {
+	key   *masterkey.Key
+	files map[string]*entities.Metadata
+}
+
+func (r *memoryFileRepository) FindAll(ctx context.Context) (*entities.Files, error) {
+	result := entities.Files{}
+	for name := range r.files {
+		result = append(result, *entities.NewFile(name, "/files/"+name, 1))
+	}
+	return &result, nil
+}
+
+func (r *memoryFileRepository) ReadMetadata(filename string, key *masterkey.Key) (*entities.Metadata, error) {
+	if !bytes.Equal(key.Vault, r.key.Vault) {
+		return nil, apperrors.ErrWrongPassword
+	}
+	meta, ok := r.files[filename]
+	if !ok {
+		return nil, apperrors.ErrFileNotFound
+	}
+	if meta == nil {
+		return &entities.Metadata{}, nil
+	}
+	return meta, nil
+}
+
+// memoryIndexRepository хранит записи в памяти вместе с ключом, которым
+// они «зашифрованы».
+type memoryIndexRepository struct {
+	records map[string][]byte
+	keys    map[string][]byte
+}
+
+func newMemoryIndexRepository() *memoryIndexRepository {
+	return &memoryIndexRepository{
+		records: make(map[string][]byte),
+		keys:    make(map[string][]byte),
+	}
+}
+
+func (r *memoryIndexRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	r.records[name] = data
+	r.keys[name] = key.Vault
+	return nil
+}
+
+func (r *memoryIndexRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	data, ok := r.records[name]
+	if !ok {
+		return nil, apperrors.ErrFileNotFound
+	}
+	if !bytes.Equal(r.keys[name], key.Vault) {
+		return nil, apperrors.ErrDecryptFailed
+	}
+	return data, nil
+}
+
+func (r *memoryIndexRepository) Delete(ctx context.Context, filename string) error {
+	if _, ok := r.records[filename]; !ok {
+		return apperrors.ErrFileNotFound
+	}
+	delete(r.records, filename)
+	return nil
+}
+
+var (
+	key      = &masterkey.Key{Password: "pass", Vault: []byte("vault key")}
+	otherKey = &masterkey.Key{Password: "other", Vault: []byte("other vault key")}
+)
+
+func TestAssign(t *testing.T) {
+	filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{}}
+	indexRepo := newMemoryIndexRepository()
+	ix := New("user", filesRepo, indexRepo)
+
+	id, err := ix.Assign("passport.pdf", key)
+	require.NoError(t, err)
+	assert.Len(t, id, 2*idSize)
+	assert.NotContains(t, id, "passport")
+
+	// Повторное шифрование записи сохраняет идентификатор
+	again, err := ix.Assign("passport.pdf", key)
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	other, err := ix.Assign("card_4111111111111111.json", key)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+
+	resolved, err := ix.Resolve("passport.pdf", key)
+	require.NoError(t, err)
+	assert.Equal(t, id, resolved)
+
+	entries, err := ix.List(key)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"passport.pdf": id, "card_4111111111111111.json": other}, entries)
+
+	_, err = ix.Assign("", key)
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("rebuilds from metadata", func(t *testing.T) {
+		filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{
+			"a1b2":    {Alias: "report.txt"},
+			"old.bin": nil,
+		}}
+		indexRepo := newMemoryIndexRepository()
+		ix := New("user", filesRepo, indexRepo)
+
+		id, err := ix.Resolve("report.txt", key)
+		require.NoError(t, err)
+		assert.Equal(t, "a1b2", id)
+
+		// Файлы прежних версий доступны под собственным именем
+		id, err = ix.Resolve("old.bin", key)
+		require.NoError(t, err)
+		assert.Equal(t, "old.bin", id)
+
+		_, err = ix.Resolve("missing.txt", key)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+
+	t.Run("picks up synced files", func(t *testing.T) {
+		filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{}}
+		indexRepo := newMemoryIndexRepository()
+		ix := New("user", filesRepo, indexRepo)
+
+		_, err := ix.Assign("local.txt", key)
+		require.NoError(t, err)
+
+		filesRepo.files["c3d4"] = &entities.Metadata{Alias: "remote.txt"}
+		id, err := ix.Resolve("remote.txt", key)
+		require.NoError(t, err)
+		assert.Equal(t, "c3d4", id)
+	})
+
+	t.Run("index under previous password is rebuilt", func(t *testing.T) {
+		filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{
+			"a1b2": {Alias: "report.txt"},
+		}}
+		indexRepo := newMemoryIndexRepository()
+		require.NoError(t, indexRepo.WriteRecord("user", []byte(`{"report.txt":"a1b2"}`), otherKey, entities.Metadata{}))
+		ix := New("user", filesRepo, indexRepo)
+
+		id, err := ix.Resolve("report.txt", key)
+		require.NoError(t, err)
+		assert.Equal(t, "a1b2", id)
+		assert.Equal(t, key.Vault, indexRepo.keys["user"])
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{
+			"a1b2": {Alias: "report.txt"},
+		}}
+		ix := New("user", filesRepo, newMemoryIndexRepository())
+
+		_, err := ix.Resolve("report.txt", otherKey)
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+	})
+}
+
+func TestRecords(t *testing.T) {
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{
+		"a1b2":    {Alias: "report.txt", Created: earlier, Labels: entities.Labels{Category: "old"}},
+		"c3d4":    {Alias: "report.txt", Created: earlier.Add(time.Hour), Labels: entities.Labels{Category: "work"}},
+		"old.bin": nil,
+	}}
+	indexRepo := newMemoryIndexRepository()
+	ix := New("user", filesRepo, indexRepo)
+
+	records, err := ix.Records(key)
+	require.NoError(t, err)
+	slices.SortFunc(records, func(a, b Record) int { return strings.Compare(a.Name, b.Name) })
+
+	// Из записей с одним именем остаётся новая
+	require.Len(t, records, 2)
+	assert.Equal(t, "old.bin", records[0].Name)
+	assert.Equal(t, "report.txt", records[1].Name)
+	assert.Equal(t, "c3d4", records[1].ID)
+	assert.Equal(t, "work", records[1].Meta.Category)
+
+	// Индекс обновлён по прочитанным метаданным
+	assert.JSONEq(t, `{"report.txt":"c3d4","old.bin":"old.bin"}`, string(indexRepo.records["user"]))
+
+	_, err = ix.Records(otherKey)
+	assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+}
+
+func TestInvalidate(t *testing.T) {
+	filesRepo := &memoryFileRepository{key: key, files: map[string]*entities.Metadata{}}
+	indexRepo := newMemoryIndexRepository()
+	ix := New("user", filesRepo, indexRepo)
+
+	assert.NoError(t, ix.Invalidate())
+
+	_, err := ix.Assign("a.txt", key)
+	require.NoError(t, err)
+	assert.NoError(t, ix.Invalidate())
+	assert.NotContains(t, indexRepo.records, "user")
+}
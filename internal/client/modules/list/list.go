@@ -0,0 +1,91 @@
+// Package list выводит записи хранилища с описанием, категорией и метками
+// и ищет записи по ним.
+package list
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/modules/index"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+type NameIndex interface {
+	Records(key *masterkey.Key) ([]index.Record, error)
+}
+
+// Filter условия поиска. Пустые условия не ограничивают поиск.
+type Filter struct {
+	Category string   // Категория записи
+	Tags     []string // Метки, которые должны быть у записи все
+	Query    string   // Подстрока имени или описания без учёта регистра
+}
+
+// Entry запись хранилища с её метками.
+type Entry struct {
+	Name string
+	entities.Labels
+}
+
+// Run возвращает отсортированные по имени записи, подходящие под фильтр.
+// Метки читаются из зашифрованных метаданных каждой записи.
+func Run(key *masterkey.Key, filter Filter, names NameIndex) ([]Entry, error) {
+	if key == nil {
+		return nil, errors.New("empty password")
+	}
+	records, err := names.Records(key)
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.ToLower(filter.Query)
+	var entries []Entry
+	for _, record := range records {
+		name := record.Name
+		// Файлы прежних версий клиента и повреждённые файлы выводятся
+		// без меток, их проверяет verify
+		var labels entities.Labels
+		if record.Meta != nil {
+			labels = record.Meta.Labels
+		}
+		if filter.Category != "" && labels.Category != filter.Category {
+			continue
+		}
+		if !labels.HasTags(filter.Tags) {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(name), query) &&
+			!strings.Contains(strings.ToLower(labels.Description), query) {
+			continue
+		}
+		entries = append(entries, Entry{Name: name, Labels: labels})
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries, nil
+}
+
+// Format возвращает записи по одной в строке: имя, категория в квадратных
+// скобках, метки с # и описание.
+func Format(entries []Entry) string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		line := e.Name
+		if e.Category != "" {
+			line += " [" + e.Category + "]"
+		}
+		for _, tag := range e.Tags {
+			line += " #" + tag
+		}
+		if e.Description != "" {
+			line += " — " + e.Description
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
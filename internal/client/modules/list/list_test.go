@@ -0,0 +1,101 @@
+package list
+
+import (
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/modules/index"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Records(key *masterkey.Key) ([]index.Record, error) {
+	args := m.Called(key)
+	records, _ := args.Get(0).([]index.Record)
+	return records, args.Error(1)
+}
+
+func TestRun(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+
+	setup := func() *MockNameIndex {
+		names := new(MockNameIndex)
+		names.On("Records", key).Return([]index.Record{
+			{Name: "report.pdf", ID: "1", Meta: &entities.Metadata{Labels: entities.Labels{
+				Description: "Налоговый отчёт", Category: "work", Tags: []string{"2024", "tax"},
+			}}},
+			{Name: "card_4111.json", ID: "2", Meta: &entities.Metadata{Labels: entities.Labels{Category: "card"}}},
+			{Name: "cred_github", ID: "3", Meta: &entities.Metadata{Labels: entities.Labels{
+				Category: "credential", Tags: []string{"work"},
+			}}},
+			{Name: "photos.tar.zst", ID: "4", Meta: &entities.Metadata{}},
+		}, nil)
+		return names
+	}
+
+	names := func(entries []Entry) []string {
+		var list []string
+		for _, e := range entries {
+			list = append(list, e.Name)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"card_4111.json", "cred_github", "photos.tar.zst", "report.pdf"}},
+		{"category", Filter{Category: "card"}, []string{"card_4111.json"}},
+		{"all tags", Filter{Tags: []string{"tax", "2024"}}, []string{"report.pdf"}},
+		{"missing tag", Filter{Tags: []string{"tax", "2023"}}, nil},
+		{"query in description", Filter{Query: "НАЛОГ"}, []string{"report.pdf"}},
+		{"query in name", Filter{Query: "git"}, []string{"cred_github"}},
+		{"combined", Filter{Category: "credential", Tags: []string{"work"}, Query: "hub"}, []string{"cred_github"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Run(key, tt.filter, setup())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(entries))
+		})
+	}
+
+	t.Run("unreadable metadata", func(t *testing.T) {
+		names := new(MockNameIndex)
+		names.On("Records", key).Return([]index.Record{{Name: "legacy.bin", ID: "1"}}, nil)
+
+		entries, err := Run(key, Filter{}, names)
+		require.NoError(t, err)
+		assert.Equal(t, []Entry{{Name: "legacy.bin"}}, entries)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		names := new(MockNameIndex)
+		names.On("Records", key).Return(nil, apperrors.ErrWrongPassword)
+
+		_, err := Run(key, Filter{}, names)
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := Run(nil, Filter{}, new(MockNameIndex))
+		assert.Error(t, err)
+	})
+}
+
+func TestFormat(t *testing.T) {
+	text := Format([]Entry{
+		{Name: "report.pdf", Labels: entities.Labels{Description: "Налоговый отчёт", Category: "work", Tags: []string{"2024", "tax"}}},
+		{Name: "photos.tar.zst"},
+	})
+	assert.Equal(t, "report.pdf [work] #2024 #tax — Налоговый отчёт\nphotos.tar.zst", text)
+}
@@ -0,0 +1,89 @@
+package note
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultEditor запускается, если переменная EDITOR не задана.
+const defaultEditor = "vi"
+
+// ReadText возвращает текст заметки из аргумента команды. Аргумент "-"
+// означает стандартный ввод, пустой аргумент — редактор из $EDITOR.
+func ReadText(arg string, stdin io.Reader) ([]byte, error) {
+	switch arg {
+	case "-":
+		return readLimited(stdin)
+	case "":
+		return Edit(os.Getenv("EDITOR"))
+	}
+	return []byte(arg), nil
+}
+
+// Edit открывает пустой временный файл в редакторе и возвращает введённый
+// текст. Редактор работает только с файлами, поэтому текст недолго лежит
+// на диске: файл доступен только владельцу, затирается и удаляется.
+func Edit(editor string) ([]byte, error) {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{defaultEditor}
+	}
+
+	f, err := os.CreateTemp("", "keeper-note-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать файл для редактора: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer wipe(path)
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ошибка редактора %s: %w", args[0], err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		clear(data)
+		return nil, fmt.Errorf("заметка больше %d КиБ, используйте encrypt", MaxSize>>10)
+	}
+	return data, nil
+}
+
+// wipe затирает содержимое файла нулями и удаляет его.
+func wipe(path string) {
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if info, err := f.Stat(); err == nil {
+			io.Copy(f, io.LimitReader(zeroReader{}, info.Size()))
+			f.Sync()
+		}
+		f.Close()
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "Не удалось удалить временный файл заметки:", path)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+// Package note хранит секретные текстовые заметки. Текст шифруется в памяти
+// и хранится так же, как карта и учётные данные.
+package note
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Category категория записи заметки по умолчанию.
+const Category = "note"
+
+// MaxSize наибольший размер заметки. Заметка целиком находится в памяти,
+// большие данные шифруются командой encrypt.
+const MaxSize = 1 << 20
+
+type FileRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+}
+
+type NameIndex interface {
+	Assign(name string, key *masterkey.Key) (string, error)
+	Resolve(name string, key *masterkey.Key) (string, error)
+}
+
+// RecordName возвращает имя записи заметки в локальном индексе.
+func RecordName(Name string) string {
+	return "note_" + Name
+}
+
+// Save шифрует текст заметки в памяти и возвращает идентификатор объекта.
+// Заметка с тем же именем заменяется.
+func Save(Name string, Text []byte, Labels entities.Labels, Key *masterkey.Key, repo FileRepository, names NameIndex) (string, error) {
+	if Key == nil {
+		return "", errors.New("empty password")
+	}
+	if Name == "" {
+		return "", errors.New("empty note name")
+	}
+	if len(Text) == 0 {
+		return "", errors.New("empty note")
+	}
+	if len(Text) > MaxSize {
+		return "", fmt.Errorf("заметка больше %d КиБ, используйте encrypt", MaxSize>>10)
+	}
+
+	name := RecordName(Name)
+	filename, err := names.Assign(name, Key)
+	if err != nil {
+		return "", err
+	}
+
+	err = repo.WriteRecord(filename, Text, Key, entities.Metadata{
+		Alias:    name,
+		MIMEType: "text/plain; charset=utf-8",
+		Labels:   Labels.OrCategory(Category),
+	})
+	if err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// Show находит заметку по имени и расшифровывает её в памяти.
+func Show(Name string, Key *masterkey.Key, repo FileRepository, names NameIndex) ([]byte, error) {
+	if Key == nil {
+		return nil, errors.New("empty password")
+	}
+	if Name == "" {
+		return nil, errors.New("empty note name")
+	}
+
+	filename, err := names.Resolve(RecordName(Name), Key)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ReadRecord(filename, Key)
+}
@@ -0,0 +1,153 @@
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	args := m.Called(name, string(data), key, meta)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	args := m.Called(name, key)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Assign(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func TestSave(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Assign", "note_wifi", key).Return("5a4b3c", nil)
+		meta := entities.Metadata{Alias: "note_wifi", MIMEType: "text/plain; charset=utf-8", Labels: entities.Labels{Category: Category}}
+		repo.On("WriteRecord", "5a4b3c", "ssid: home\npass: 123", key, meta).Return(nil)
+
+		id, err := Save("wifi", []byte("ssid: home\npass: 123"), entities.Labels{}, key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, "5a4b3c", id)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("user category", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Assign", "note_wifi", key).Return("5a4b3c", nil)
+		labels := entities.Labels{Description: "домашняя сеть", Category: "home", Tags: []string{"wifi"}}
+		meta := entities.Metadata{Alias: "note_wifi", MIMEType: "text/plain; charset=utf-8", Labels: labels}
+		repo.On("WriteRecord", "5a4b3c", "ssid: home", key, meta).Return(nil)
+
+		_, err := Save("wifi", []byte("ssid: home"), labels, key, repo, names)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+
+		_, err := Save("", []byte("text"), entities.Labels{}, key, repo, names)
+		assert.Error(t, err)
+		_, err = Save("wifi", nil, entities.Labels{}, key, repo, names)
+		assert.Error(t, err)
+		_, err = Save("wifi", []byte("text"), entities.Labels{}, nil, repo, names)
+		assert.Error(t, err)
+		_, err = Save("wifi", make([]byte, MaxSize+1), entities.Labels{}, key, repo, names)
+		assert.ErrorContains(t, err, "encrypt")
+		names.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything)
+	})
+}
+
+func TestShow(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "note_wifi", key).Return("5a4b3c", nil)
+		repo.On("ReadRecord", "5a4b3c", key).Return([]byte("ssid: home"), nil)
+
+		text, err := Show("wifi", key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, "ssid: home", string(text))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		names := new(MockNameIndex)
+		names.On("Resolve", "note_missing", key).Return("", apperrors.ErrFileNotFound)
+
+		_, err := Show("missing", key, new(MockFileRepository), names)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+}
+
+func TestReadText(t *testing.T) {
+	t.Run("argument", func(t *testing.T) {
+		text, err := ReadText("secret text", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "secret text", string(text))
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		text, err := ReadText("-", strings.NewReader("line 1\nline 2\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "line 1\nline 2\n", string(text))
+	})
+
+	t.Run("stdin too large", func(t *testing.T) {
+		_, err := ReadText("-", strings.NewReader(strings.Repeat("x", MaxSize+1)))
+		assert.ErrorContains(t, err, "encrypt")
+	})
+
+	t.Run("editor", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("редактор запускается shell-скриптом")
+		}
+		dir := t.TempDir()
+		saved := filepath.Join(dir, "path")
+		editor := filepath.Join(dir, "editor.sh")
+		script := "#!/bin/sh\nprintf '%s' \"$1\" > " + saved + "\nprintf 'from editor' > \"$1\"\n"
+		require.NoError(t, os.WriteFile(editor, []byte(script), 0700))
+		t.Setenv("EDITOR", editor)
+
+		text, err := ReadText("", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "from editor", string(text))
+
+		// Временный файл редактора удалён
+		path, err := os.ReadFile(saved)
+		require.NoError(t, err)
+		assert.NoFileExists(t, string(path))
+	})
+}
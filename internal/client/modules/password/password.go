@@ -0,0 +1,27 @@
+// Package password заменяет учётные данные пользователя на сервере.
+// Сервер хранит ключ авторизации, полученный из мастер-пароля (см.
+// masterkey), поэтому при смене пароля или параметров Argon2id ключ
+// авторизации нужно заменить и на сервере. Запрос, который должен
+// поддерживать сервер, описан в README, раздел «Требования к серверу».
+package password
+
+import "errors"
+
+type HTTPClient interface {
+	Post(endpoint string, body any) ([]byte, error)
+}
+
+// Run заменяет ключ авторизации пользователя на сервере. Запрос
+// подтверждается сохранённым токеном входа, поэтому прежний ключ
+// авторизации не нужен.
+func Run(username string, auth string, http HTTPClient) error {
+	if auth == "" {
+		return errors.New("empty auth key")
+	}
+	postData := map[string]interface{}{
+		"username": username,
+		"password": auth,
+	}
+	_, err := http.Post("/password", postData)
+	return err
+}
@@ -0,0 +1,45 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHTTPClient struct {
+	mock.Mock
+}
+
+func (m *MockHTTPClient) Post(endpoint string, body any) ([]byte, error) {
+	args := m.Called(endpoint, body)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func TestRun(t *testing.T) {
+	body := map[string]interface{}{
+		"username": "user",
+		"password": "new auth key",
+	}
+
+	t.Run("success", func(t *testing.T) {
+		http := new(MockHTTPClient)
+		http.On("Post", "/password", body).Return([]byte{}, nil).Once()
+
+		assert.NoError(t, Run("user", "new auth key", http))
+		http.AssertExpectations(t)
+	})
+
+	t.Run("post error", func(t *testing.T) {
+		http := new(MockHTTPClient)
+		http.On("Post", "/password", body).Return([]byte{}, assert.AnError).Once()
+
+		assert.ErrorIs(t, Run("user", "new auth key", http), assert.AnError)
+	})
+
+	t.Run("empty auth key", func(t *testing.T) {
+		http := new(MockHTTPClient)
+		assert.Error(t, Run("user", "", http))
+		http.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,58 @@
+// Package profile хранит локальные настройки учётной записи, от которых
+// зависят её ключи: параметры Argon2id мастер-пароля. Параметры
+// сохраняются при регистрации, входе и смене пароля, и изменение
+// kdf_time, kdf_memory или kdf_threads в настройках не меняет ключи
+// незаметно для пользователя.
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+type Repository interface {
+	Save(ctx context.Context, filename string, data io.Reader) error
+	GetFileContent(ctx context.Context, filename string) (string, error)
+}
+
+// Profile настройки учётной записи.
+type Profile struct {
+	KDF masterkey.Params `json:"kdf"`
+}
+
+// Load возвращает профиль пользователя или nil, если профиль не сохранён.
+func Load(ctx context.Context, username string, repo Repository) (*Profile, error) {
+	content, err := repo.GetFileContent(ctx, fileName(username))
+	if errors.Is(err, apperrors.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p Profile
+	if err := json.Unmarshal([]byte(content), &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %v", err)
+	}
+	return &p, nil
+}
+
+// Save сохраняет профиль пользователя.
+func Save(ctx context.Context, username string, p Profile, repo Repository) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return repo.Save(ctx, fileName(username), strings.NewReader(string(data)))
+}
+
+func fileName(username string) string {
+	return username + ".json"
+}
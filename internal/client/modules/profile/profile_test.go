@@ -0,0 +1,57 @@
+package profile
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository хранит профили в памяти.
+type memoryRepository map[string]string
+
+func (r memoryRepository) Save(ctx context.Context, filename string, data io.Reader) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	r[filename] = string(content)
+	return nil
+}
+
+func (r memoryRepository) GetFileContent(ctx context.Context, filename string) (string, error) {
+	content, ok := r[filename]
+	if !ok {
+		return "", apperrors.ErrFileNotFound
+	}
+	return content, nil
+}
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryRepository{}
+
+	p, err := Load(ctx, "user", repo)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	saved := Profile{KDF: masterkey.Params{Time: 2, Memory: 1024, Threads: 1}}
+	require.NoError(t, Save(ctx, "user", saved, repo))
+	assert.JSONEq(t, `{"kdf":{"time":2,"memory":1024,"threads":1}}`, repo["user.json"])
+
+	p, err = Load(ctx, "user", repo)
+	require.NoError(t, err)
+	assert.Equal(t, &saved, p)
+
+	p, err = Load(ctx, "other", repo)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	repo["broken.json"] = "not json"
+	_, err = Load(ctx, "broken", repo)
+	assert.Error(t, err)
+}
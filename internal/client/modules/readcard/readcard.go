@@ -0,0 +1,100 @@
+package readcard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aube/keeper/internal/client/modules/card"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+type FileRepository interface {
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+}
+
+type NameIndex interface {
+	Resolve(name string, key *masterkey.Key) (string, error)
+}
+
+// Run находит карту по номеру в локальном индексе, расшифровывает её
+// в памяти и выводит на экран. Необязательные поля выводятся, только если
+// они заполнены, а истёкший срок действия отмечается предупреждением.
+func Run(Number string, Key *masterkey.Key, repo FileRepository, names NameIndex) error {
+	if Key == nil {
+		return errors.New("empty password")
+	}
+	if Number == "" {
+		return errors.New("empty card number")
+	}
+
+	id, err := resolve(Number, Key, names)
+	if err != nil {
+		return err
+	}
+
+	data, err := repo.ReadRecord(id, Key)
+	if err != nil {
+		return err
+	}
+
+	var c card.CardJSON
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("failed to unmarshal card: %v", err)
+	}
+
+	fmt.Println(Format(&c, time.Now()))
+
+	return nil
+}
+
+// resolve находит карту по нормализованному номеру, а если её нет, по имени,
+// под которым её сохраняли прежние версии.
+func resolve(Number string, Key *masterkey.Key, names NameIndex) (string, error) {
+	id, err := names.Resolve(card.RecordName(Number), Key)
+	legacy := card.LegacyRecordName(Number)
+	if !errors.Is(err, apperrors.ErrFileNotFound) || legacy == card.RecordName(Number) {
+		return id, err
+	}
+	if id, legacyErr := names.Resolve(legacy, Key); legacyErr == nil {
+		return id, nil
+	}
+	return "", err
+}
+
+// Format возвращает карту в виде для вывода на экран. Платёжная система
+// записей прежних версий определяется по номеру.
+func Format(c *card.CardJSON, now time.Time) string {
+	brand := c.Brand
+	if brand == "" {
+		brand = card.Brand(c.Number)
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "Номер:", c.Number)
+	if brand != "" {
+		fmt.Fprintln(&b, "Платёжная система:", brand)
+	}
+	date := c.Date
+	if c.Expired(now) {
+		date += " (срок действия истёк)"
+	}
+	fmt.Fprintln(&b, "Срок действия:", date)
+	fmt.Fprintln(&b, "CVV:", c.CVV)
+	if c.Holder != "" {
+		fmt.Fprintln(&b, "Владелец:", c.Holder)
+	}
+	if c.Bank != "" {
+		fmt.Fprintln(&b, "Банк:", c.Bank)
+	}
+	if c.PIN != "" {
+		fmt.Fprintln(&b, "PIN:", c.PIN)
+	}
+	if c.Notes != "" {
+		fmt.Fprintln(&b, "Заметки:", c.Notes)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
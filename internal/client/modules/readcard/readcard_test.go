@@ -0,0 +1,132 @@
+package readcard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aube/keeper/internal/client/modules/card"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	args := m.Called(name, key)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func TestRun(t *testing.T) {
+	key := &masterkey.Key{Password: "secure", Vault: []byte("vault")}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockIndex.On("Resolve", "card_1234567890123456.json", key).Return("7e6d5c", nil).Once()
+		mockRepo.On("ReadRecord", "7e6d5c", key).Return([]byte(`{"number":"1234 5678 9012 3456","date":"12/25","cvv":"123"}`), nil).Once()
+
+		err := Run("1234 5678 9012 3456", key, mockRepo, mockIndex)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+		mockIndex.AssertExpectations(t)
+	})
+
+	t.Run("unknown card", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockIndex.On("Resolve", "card_1111.json", key).Return("", apperrors.ErrFileNotFound).Once()
+
+		err := Run("1111", key, mockRepo, mockIndex)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+		mockRepo.AssertNotCalled(t, "ReadRecord", mock.Anything, mock.Anything)
+	})
+
+	t.Run("legacy name", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockIndex.On("Resolve", "card_1234567890123456.json", key).Return("", apperrors.ErrFileNotFound).Once()
+		mockIndex.On("Resolve", "card_1234-5678-9012-3456.json", key).Return("7e6d5c", nil).Once()
+		mockRepo.On("ReadRecord", "7e6d5c", key).Return([]byte(`{"number":"1234-5678-9012-3456","date":"12/25","cvv":"123"}`), nil).Once()
+
+		err := Run("1234-5678-9012-3456", key, mockRepo, mockIndex)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+		mockIndex.AssertExpectations(t)
+	})
+
+	t.Run("unknown card with legacy name", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockIndex.On("Resolve", "card_1111.json", key).Return("", apperrors.ErrFileNotFound).Once()
+		mockIndex.On("Resolve", "card_11-11.json", key).Return("", apperrors.ErrFileNotFound).Once()
+
+		err := Run("11-11", key, mockRepo, mockIndex)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+		mockRepo.AssertNotCalled(t, "ReadRecord", mock.Anything, mock.Anything)
+		mockIndex.AssertExpectations(t)
+	})
+
+	t.Run("invalid record", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+		mockIndex.On("Resolve", "card_1111.json", key).Return("7e6d5c", nil).Once()
+		mockRepo.On("ReadRecord", "7e6d5c", key).Return([]byte(`invalid`), nil).Once()
+
+		err := Run("1111", key, mockRepo, mockIndex)
+		assert.Error(t, err)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.EqualError(t, Run("1111", nil, nil, nil), "empty password")
+		assert.EqualError(t, Run("", key, nil, nil), "empty card number")
+	})
+}
+
+func TestFormat(t *testing.T) {
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("full", func(t *testing.T) {
+		text := Format(&card.CardJSON{
+			Number: "4111111111111111",
+			Date:   "12/25",
+			CVV:    "123",
+			Brand:  card.BrandVisa,
+			Holder: "IVAN IVANOV",
+			Bank:   "Example Bank",
+			PIN:    "1234",
+			Notes:  "зарплатная",
+		}, now)
+		assert.Equal(t, "Номер: 4111111111111111\n"+
+			"Платёжная система: Visa\n"+
+			"Срок действия: 12/25\n"+
+			"CVV: 123\n"+
+			"Владелец: IVAN IVANOV\n"+
+			"Банк: Example Bank\n"+
+			"PIN: 1234\n"+
+			"Заметки: зарплатная", text)
+	})
+
+	t.Run("legacy expired", func(t *testing.T) {
+		text := Format(&card.CardJSON{Number: "5500 0000 0000 0004", Date: "05/25", CVV: "123"}, now)
+		assert.Equal(t, "Номер: 5500 0000 0000 0004\n"+
+			"Платёжная система: Mastercard\n"+
+			"Срок действия: 05/25 (срок действия истёк)\n"+
+			"CVV: 123", text)
+	})
+}
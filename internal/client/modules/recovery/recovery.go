@@ -0,0 +1,171 @@
+// Package recovery разделяет ключ хранилища на доли по схеме Шамира и
+// восстанавливает его из долей, если мастер-пароль забыт.
+//
+// Доля записывается строкой ks1-<порог>-<номер>-<значение в hex>. Доли
+// относятся к текущему ключу хранилища: после смены пароля или ключевого
+// файла их нужно получить заново.
+package recovery
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/aube/keeper/internal/client/utils/shamir"
+)
+
+// sharePrefix версия формата доли.
+const sharePrefix = "ks1"
+
+type FileRepository interface {
+	FindAll(ctx context.Context) (*entities.Files, error)
+	CheckKey(filename string, key *masterkey.Key) error
+}
+
+// Split разделяет ключ хранилища на n долей, из которых для восстановления
+// достаточно любых threshold.
+func Split(key *masterkey.Key, n, threshold int) ([]string, error) {
+	if key == nil {
+		return nil, errors.New("empty password")
+	}
+
+	shares, err := shamir.Split(key.Vault, n, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(shares))
+	for i, s := range shares {
+		result[i] = fmt.Sprintf("%s-%d-%d-%s", sharePrefix, threshold, s.X, hex.EncodeToString(s.Y))
+	}
+	return result, nil
+}
+
+// WriteShares записывает каждую долю в отдельный файл каталога dir
+// и возвращает пути файлов. Существующие файлы не перезаписываются.
+func WriteShares(dir string, shares []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(shares))
+	for i, share := range shares {
+		path := filepath.Join(dir, fmt.Sprintf("share-%d.txt", i+1))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return paths, fmt.Errorf("не удалось создать файл доли: %w", err)
+		}
+		_, err = f.WriteString(share + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("ошибка записи доли: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Recover восстанавливает ключ хранилища из долей. Каждое значение —
+// доля или путь к файлу с долей. Восстановленный ключ проверяется
+// на файлах хранилища.
+func Recover(values []string, repo FileRepository) (*masterkey.Key, error) {
+	shares := make([]shamir.Share, 0, len(values))
+	threshold := 0
+	for i, value := range values {
+		share, t, err := readShare(value)
+		if err != nil {
+			return nil, fmt.Errorf("доля %d: %w", i+1, err)
+		}
+		if threshold != 0 && t != threshold {
+			return nil, errors.New("доли получены при разных разделениях ключа")
+		}
+		threshold = t
+		shares = append(shares, share)
+	}
+	if len(shares) < threshold || len(shares) == 0 {
+		return nil, fmt.Errorf("недостаточно долей: указано %d, нужно %d", len(shares), threshold)
+	}
+
+	vault, err := shamir.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	key := &masterkey.Key{Vault: vault}
+
+	if err := check(key, repo); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// check проверяет, что ключом расшифровывается хотя бы один файл хранилища.
+// Файлы прежнего формата зашифрованы паролем и ключом хранилища не
+// расшифровываются.
+func check(key *masterkey.Key, repo FileRepository) error {
+	files, err := repo.FindAll(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(*files) == 0 {
+		return errors.New("в хранилище нет файлов для восстановления")
+	}
+
+	for _, file := range *files {
+		if repo.CheckKey(file.Name, key) == nil {
+			return nil
+		}
+	}
+	return errors.New("доли не подходят к ключу хранилища")
+}
+
+// readShare читает долю из строки или из файла. Ошибки не содержат
+// значения: это может быть доля с опечаткой, которая попала бы в журнал.
+func readShare(value string) (shamir.Share, int, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, sharePrefix+"-") {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				err = pathErr.Err
+			}
+			return shamir.Share{}, 0, fmt.Errorf("не удалось прочитать долю из файла: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+	return parseShare(value)
+}
+
+// parseShare разбирает строку доли.
+func parseShare(value string) (shamir.Share, int, error) {
+	invalid := fmt.Errorf("неверный формат доли длиной %d символов", len(value))
+
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || parts[0] != sharePrefix {
+		return shamir.Share{}, 0, invalid
+	}
+	threshold, err := strconv.Atoi(parts[1])
+	if err != nil || threshold < 2 {
+		return shamir.Share{}, 0, invalid
+	}
+	x, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil || x == 0 {
+		return shamir.Share{}, 0, invalid
+	}
+	y, err := hex.DecodeString(parts[3])
+	if err != nil || len(y) == 0 {
+		return shamir.Share{}, 0, invalid
+	}
+
+	return shamir.Share{X: byte(x), Y: y}, threshold, nil
+}
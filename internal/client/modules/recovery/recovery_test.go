@@ -0,0 +1,137 @@
+package recovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) FindAll(ctx context.Context) (*entities.Files, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*entities.Files), args.Error(1)
+}
+
+func (m *MockFileRepository) CheckKey(filename string, key *masterkey.Key) error {
+	args := m.Called(filename, key)
+	return args.Error(0)
+}
+
+var testKey = &masterkey.Key{Password: "pass", Vault: []byte("0123456789abcdef0123456789abcdef")}
+
+func files(names ...string) *entities.Files {
+	result := entities.Files{}
+	for _, name := range names {
+		result = append(result, *entities.NewFile(name, "/files/"+name, 1))
+	}
+	return &result
+}
+
+func TestSplitRecover(t *testing.T) {
+	ctx := context.Background()
+
+	shares, err := Split(testKey, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	assert.True(t, strings.HasPrefix(shares[0], "ks1-3-1-"))
+
+	// Восстановленный ключ содержит только ключ хранилища
+	recovered := &masterkey.Key{Vault: testKey.Vault}
+
+	t.Run("recover", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockRepo.On("FindAll", ctx).Return(files("legacy", "a1"), nil).Once()
+		mockRepo.On("CheckKey", "legacy", recovered).Return(apperrors.ErrWrongPassword).Once()
+		mockRepo.On("CheckKey", "a1", recovered).Return(nil).Once()
+
+		key, err := Recover([]string{shares[4], shares[0], shares[2]}, mockRepo)
+		require.NoError(t, err)
+		assert.Equal(t, recovered, key)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("from files", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "shares")
+		paths, err := WriteShares(dir, shares)
+		require.NoError(t, err)
+		require.Len(t, paths, 5)
+
+		fi, err := os.Stat(paths[0])
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+		_, err = WriteShares(dir, shares)
+		assert.Error(t, err)
+
+		mockRepo := new(MockFileRepository)
+		mockRepo.On("FindAll", ctx).Return(files("a1"), nil).Once()
+		mockRepo.On("CheckKey", "a1", recovered).Return(nil).Once()
+
+		key, err := Recover([]string{paths[1], shares[3], paths[2]}, mockRepo)
+		require.NoError(t, err)
+		assert.Equal(t, recovered, key)
+	})
+
+	t.Run("not enough shares", func(t *testing.T) {
+		_, err := Recover(shares[:2], nil)
+		assert.EqualError(t, err, "недостаточно долей: указано 2, нужно 3")
+	})
+
+	t.Run("shares do not match", func(t *testing.T) {
+		other, err := Split(&masterkey.Key{Vault: []byte("fedcba9876543210fedcba9876543210")}, 5, 3)
+		require.NoError(t, err)
+
+		mockRepo := new(MockFileRepository)
+		mockRepo.On("FindAll", ctx).Return(files("a1"), nil).Once()
+		mockRepo.On("CheckKey", "a1", mock.Anything).Return(apperrors.ErrWrongPassword).Once()
+
+		_, err = Recover([]string{shares[0], shares[1], other[2]}, mockRepo)
+		assert.EqualError(t, err, "доли не подходят к ключу хранилища")
+	})
+
+	t.Run("different thresholds", func(t *testing.T) {
+		other, err := Split(testKey, 5, 2)
+		require.NoError(t, err)
+		_, err = Recover([]string{shares[0], other[1]}, nil)
+		assert.EqualError(t, err, "доли получены при разных разделениях ключа")
+	})
+
+	t.Run("invalid share", func(t *testing.T) {
+		_, err := Recover([]string{shares[0], "ks1-3-0-abcd"}, nil)
+		assert.EqualError(t, err, "доля 2: неверный формат доли длиной 12 символов")
+
+		_, err = Recover([]string{"missing-share.txt"}, nil)
+		assert.ErrorContains(t, err, "доля 1: не удалось прочитать долю")
+		assert.NotContains(t, err.Error(), "missing-share.txt")
+	})
+
+	t.Run("errors do not echo shares", func(t *testing.T) {
+		secret := shares[0][strings.LastIndex(shares[0], "-")+1:][:8]
+
+		// Доля с опечаткой в префиксе читается как путь к файлу
+		_, err := Recover([]string{"x" + shares[0]}, nil)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), secret)
+
+		_, err = Recover([]string{shares[0] + "zz"}, nil)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), secret)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := Split(nil, 5, 3)
+		assert.EqualError(t, err, "empty password")
+	})
+}
@@ -0,0 +1,313 @@
+package rekey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/modules/profile"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Смена пароля проходит в четыре этапа, состояние которых хранится в журнале:
+//
+//  1. prepare — копии всех файлов под новым паролем пишутся в отдельный
+//     каталог, хранилище не меняется. Прерванный этап продолжается с места
+//     остановки при повторном запуске.
+//  2. credential — ключ авторизации нового пароля заменяет прежний на
+//     сервере. Хранилище меняется только после того, как сервер принял
+//     новый ключ: иначе хранилище открывалось бы новым паролем, а вход на
+//     сервер требовал бы прежний.
+//  3. commit — копии переносятся в хранилище, а параметры Argon2id нового
+//     ключа сохраняются в профиль учётной записи. Этап не требует паролей
+//     и доводится до конца при следующем запуске клиента (см. Recover),
+//     поэтому хранилище не остаётся наполовину перешифрованным.
+//  4. upload — изменённые файлы отправляются на сервер.
+const (
+	StatePrepare    = "prepare"
+	StateCredential = "credential"
+	StateCommit     = "commit"
+	StateUpload     = "upload"
+)
+
+type FileRepository interface {
+	FindAll(ctx context.Context) (*entities.Files, error)
+	RekeyFile(inputName, outputPath string, oldKey, newKey *masterkey.Key) error
+	NeedsPassword(filename string) (bool, error)
+	ReplaceFile(filename, sourcePath string) error
+}
+
+type StagingRepository interface {
+	GetPath(filename string) string
+	Exists(filename string) bool
+	CheckKey(filename string, key *masterkey.Key) error
+}
+
+type StateRepository interface {
+	Save(ctx context.Context, filename string, data io.Reader) error
+	GetFileContent(ctx context.Context, filename string) (string, error)
+	Delete(ctx context.Context, filename string) error
+}
+
+// Uploader отправляет файл из хранилища на сервер.
+type Uploader func(filename string) error
+
+// CredentialUpdater заменяет ключ авторизации пользователя на сервере.
+type CredentialUpdater func(auth string) error
+
+// Journal журнал смены пароля.
+type Journal struct {
+	State    string   `json:"state"`
+	Files    []string `json:"files"`
+	Uploaded []string `json:"uploaded"`
+	// Skipped файлы, которые расшифровываются только прежним паролем и не
+	// перешифровываются ключом, восстановленным из долей.
+	Skipped []string `json:"skipped,omitempty"`
+	// Auth ключ авторизации нового пароля, если он отличается от прежнего.
+	// Хранится, чтобы прерванную смену пароля можно было завершить без
+	// паролей, и защищён так же, как токен входа.
+	Auth string `json:"auth,omitempty"`
+	// KDF параметры Argon2id нового ключа, которые сохраняются в профиль
+	// при переносе файлов.
+	KDF *masterkey.Params `json:"kdf,omitempty"`
+}
+
+func Run(
+	username string,
+	oldKey *masterkey.Key,
+	newKey *masterkey.Key,
+	filesRepo FileRepository,
+	stagingRepo StagingRepository,
+	stateRepo StateRepository,
+	profileRepo profile.Repository,
+	upload Uploader,
+	credential CredentialUpdater,
+) error {
+	ctx := context.Background()
+
+	journal, err := LoadJournal(ctx, username, stateRepo)
+	if err != nil {
+		return err
+	}
+
+	if journal == nil || journal.State == StatePrepare {
+		if oldKey == nil {
+			return errors.New("empty password")
+		}
+		if newKey == nil {
+			return errors.New("empty new password")
+		}
+		if bytes.Equal(oldKey.Vault, newKey.Vault) {
+			return errors.New("new password must differ from the old one")
+		}
+
+		journal, err = prepare(ctx, username, journal, oldKey, newKey, filesRepo, stagingRepo, stateRepo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if journal.State == StateCredential {
+		if journal.Auth != "" {
+			if err := credential(journal.Auth); err != nil {
+				return fmt.Errorf("сервер не принял новый пароль, хранилище не изменено, повторите rekey: %w", err)
+			}
+		}
+		journal.State = StateCommit
+		if err := saveJournal(ctx, username, journal, stateRepo); err != nil {
+			return err
+		}
+	}
+
+	if journal.State == StateCommit {
+		if err := commit(ctx, username, journal, filesRepo, stagingRepo, stateRepo, profileRepo); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range journal.Files {
+		if slices.Contains(journal.Uploaded, name) {
+			continue
+		}
+		if err := upload(name); err != nil {
+			return fmt.Errorf("пароль изменён, но %s не отправлен на сервер, повторите rekey: %w", name, err)
+		}
+		journal.Uploaded = append(journal.Uploaded, name)
+		if err := saveJournal(ctx, username, journal, stateRepo); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Пароль изменён, файлов перешифровано:", len(journal.Files))
+	if len(journal.Skipped) > 0 {
+		fmt.Println("Не перешифрованы файлы прежних версий, которые открываются только прежним паролем:", strings.Join(journal.Skipped, ", "))
+	}
+
+	return stateRepo.Delete(ctx, journalName(username))
+}
+
+// Recover доводит до конца перенос файлов, если клиент был остановлен
+// на этапе commit. Вызывается при каждом запуске клиента.
+func Recover(
+	username string,
+	filesRepo FileRepository,
+	stagingRepo StagingRepository,
+	stateRepo StateRepository,
+	profileRepo profile.Repository,
+) error {
+	ctx := context.Background()
+
+	journal, err := LoadJournal(ctx, username, stateRepo)
+	if err != nil || journal == nil || journal.State != StateCommit {
+		return err
+	}
+
+	if err := commit(ctx, username, journal, filesRepo, stagingRepo, stateRepo, profileRepo); err != nil {
+		return err
+	}
+
+	fmt.Println("Завершена прерванная смена пароля. Запустите rekey, чтобы отправить файлы на сервер")
+
+	return nil
+}
+
+func prepare(
+	ctx context.Context,
+	username string,
+	journal *Journal,
+	oldKey *masterkey.Key,
+	newKey *masterkey.Key,
+	filesRepo FileRepository,
+	stagingRepo StagingRepository,
+	stateRepo StateRepository,
+) (*Journal, error) {
+	files, err := filesRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if journal == nil {
+		journal = &Journal{State: StatePrepare}
+	}
+	// Ключ авторизации не зависит от ключевого файла и не меняется, если
+	// меняется только ключевой файл
+	journal.Auth = ""
+	if newKey.Auth != oldKey.Auth {
+		journal.Auth = newKey.Auth
+	}
+	journal.KDF = nil
+	if newKey.Params != (masterkey.Params{}) {
+		journal.KDF = &newKey.Params
+	}
+	// Файлы, появившиеся после прерванного запуска, тоже перешифровываются
+	for _, f := range *files {
+		if slices.Contains(journal.Files, f.Name) || slices.Contains(journal.Skipped, f.Name) {
+			continue
+		}
+		// У ключа, восстановленного из долей, нет пароля, и файлы прежних
+		// версий, зашифрованные паролем, им не открываются
+		if oldKey.Password == "" {
+			needs, err := filesRepo.NeedsPassword(f.Name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			if needs {
+				journal.Skipped = append(journal.Skipped, f.Name)
+				continue
+			}
+		}
+		journal.Files = append(journal.Files, f.Name)
+	}
+	if err := saveJournal(ctx, username, journal, stateRepo); err != nil {
+		return nil, err
+	}
+
+	for _, name := range journal.Files {
+		// Копия, сделанная при прерванном запуске, уже готова
+		if stagingRepo.Exists(name) && stagingRepo.CheckKey(name, newKey) == nil {
+			continue
+		}
+
+		if err := filesRepo.RekeyFile(name, stagingRepo.GetPath(name), oldKey, newKey); err != nil {
+			if errors.Is(err, apperrors.ErrFileNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	journal.State = StateCredential
+	if err := saveJournal(ctx, username, journal, stateRepo); err != nil {
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+func commit(
+	ctx context.Context,
+	username string,
+	journal *Journal,
+	filesRepo FileRepository,
+	stagingRepo StagingRepository,
+	stateRepo StateRepository,
+	profileRepo profile.Repository,
+) error {
+	for _, name := range journal.Files {
+		// Отсутствующая копия уже перенесена
+		if !stagingRepo.Exists(name) {
+			continue
+		}
+		if err := filesRepo.ReplaceFile(name, stagingRepo.GetPath(name)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	// Файлы хранилища зашифрованы новым ключом, и следующие ключи нужно
+	// получать с его параметрами
+	if journal.KDF != nil {
+		if err := profile.Save(ctx, username, profile.Profile{KDF: *journal.KDF}, profileRepo); err != nil {
+			return err
+		}
+	}
+
+	journal.State = StateUpload
+	return saveJournal(ctx, username, journal, stateRepo)
+}
+
+// LoadJournal возвращает журнал незавершённой смены пароля или nil.
+func LoadJournal(ctx context.Context, username string, stateRepo StateRepository) (*Journal, error) {
+	content, err := stateRepo.GetFileContent(ctx, journalName(username))
+	if errors.Is(err, apperrors.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var journal Journal
+	if err := json.Unmarshal([]byte(content), &journal); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rekey journal: %v", err)
+	}
+
+	return &journal, nil
+}
+
+func saveJournal(ctx context.Context, username string, journal *Journal, stateRepo StateRepository) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return stateRepo.Save(ctx, journalName(username), strings.NewReader(string(data)))
+}
+
+func journalName(username string) string {
+	return username + ".json"
+}
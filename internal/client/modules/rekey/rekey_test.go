@@ -0,0 +1,418 @@
+package rekey
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) FindAll(ctx context.Context) (*entities.Files, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*entities.Files), args.Error(1)
+}
+
+func (m *MockFileRepository) RekeyFile(inputName, outputPath string, oldKey, newKey *masterkey.Key) error {
+	args := m.Called(inputName, outputPath, oldKey, newKey)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) NeedsPassword(filename string) (bool, error) {
+	args := m.Called(filename)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) ReplaceFile(filename, sourcePath string) error {
+	args := m.Called(filename, sourcePath)
+	return args.Error(0)
+}
+
+type MockStagingRepository struct {
+	mock.Mock
+}
+
+func (m *MockStagingRepository) GetPath(filename string) string {
+	return "/staging/" + filename
+}
+
+func (m *MockStagingRepository) Exists(filename string) bool {
+	args := m.Called(filename)
+	return args.Bool(0)
+}
+
+func (m *MockStagingRepository) CheckKey(filename string, key *masterkey.Key) error {
+	args := m.Called(filename, key)
+	return args.Error(0)
+}
+
+// memoryStateRepository хранит журнал в памяти.
+type memoryStateRepository struct {
+	files map[string]string
+}
+
+func newMemoryStateRepository() *memoryStateRepository {
+	return &memoryStateRepository{files: make(map[string]string)}
+}
+
+func (r *memoryStateRepository) Save(ctx context.Context, filename string, data io.Reader) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	r.files[filename] = string(content)
+	return nil
+}
+
+func (r *memoryStateRepository) GetFileContent(ctx context.Context, filename string) (string, error) {
+	content, ok := r.files[filename]
+	if !ok {
+		return "", apperrors.ErrFileNotFound
+	}
+	return content, nil
+}
+
+func (r *memoryStateRepository) Delete(ctx context.Context, filename string) error {
+	delete(r.files, filename)
+	return nil
+}
+
+func (r *memoryStateRepository) journal(t *testing.T) *Journal {
+	content, ok := r.files[journalName("user")]
+	if !ok {
+		return nil
+	}
+	var j Journal
+	require.NoError(t, json.Unmarshal([]byte(content), &j))
+	return &j
+}
+
+func files(names ...string) *entities.Files {
+	result := entities.Files{}
+	for _, name := range names {
+		result = append(result, *entities.NewFile(name, "/files/"+name, 1))
+	}
+	return &result
+}
+
+var (
+	oldKey = &masterkey.Key{Password: "old", Auth: "old auth", Vault: []byte("old vault key")}
+	newKey = &masterkey.Key{Password: "new", Auth: "new auth", Vault: []byte("new vault key"), Params: masterkey.Params{Time: 2, Memory: 2048, Threads: 2}}
+)
+
+// credentials запоминает ключи авторизации, отправленные на сервер.
+type credentials []string
+
+func (c *credentials) update(auth string) error {
+	*c = append(*c, auth)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		var uploaded []string
+		var sent credentials
+
+		filesRepo.On("FindAll", ctx).Return(files("a.bin", "card_1.json"), nil).Once()
+		for _, name := range []string{"a.bin", "card_1.json"} {
+			stagingRepo.On("Exists", name).Return(false).Once()
+			filesRepo.On("RekeyFile", name, "/staging/"+name, oldKey, newKey).Return(nil).Once()
+			stagingRepo.On("Exists", name).Return(true).Once()
+			filesRepo.On("ReplaceFile", name, "/staging/"+name).Return(nil).Once()
+		}
+
+		err := Run("user", oldKey, newKey, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error {
+			uploaded = append(uploaded, name)
+			return nil
+		}, sent.update)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.bin", "card_1.json"}, uploaded)
+		assert.Equal(t, credentials{"new auth"}, sent)
+		assert.Nil(t, stateRepo.journal(t))
+		assert.JSONEq(t, `{"kdf":{"time":2,"memory":2048,"threads":2}}`, profileRepo.files["user.json"])
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("recovered key skips password files", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		recovered := &masterkey.Key{Vault: []byte("old vault key")}
+		var uploaded []string
+		var sent credentials
+
+		// Файл без заголовка и контейнер v3 с ключом Argon2id открываются
+		// только паролем, которого у восстановленного ключа нет
+		filesRepo.On("FindAll", ctx).Return(files("legacy.bin", "v3.bin", "a.bin"), nil).Once()
+		filesRepo.On("NeedsPassword", "legacy.bin").Return(true, nil).Once()
+		filesRepo.On("NeedsPassword", "v3.bin").Return(true, nil).Once()
+		filesRepo.On("NeedsPassword", "a.bin").Return(false, nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(false).Once()
+		filesRepo.On("RekeyFile", "a.bin", "/staging/a.bin", recovered, newKey).Return(nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(true).Once()
+		filesRepo.On("ReplaceFile", "a.bin", "/staging/a.bin").Return(nil).Once()
+
+		err := Run("user", recovered, newKey, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error {
+			uploaded = append(uploaded, name)
+			return nil
+		}, sent.update)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.bin"}, uploaded)
+		assert.Equal(t, credentials{"new auth"}, sent)
+		assert.Nil(t, stateRepo.journal(t))
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("rekey error keeps vault untouched", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+
+		filesRepo.On("FindAll", ctx).Return(files("a.bin", "b.bin"), nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(false).Once()
+		filesRepo.On("RekeyFile", "a.bin", "/staging/a.bin", oldKey, newKey).Return(nil).Once()
+		stagingRepo.On("Exists", "b.bin").Return(false).Once()
+		filesRepo.On("RekeyFile", "b.bin", "/staging/b.bin", oldKey, newKey).Return(apperrors.ErrDecryptFailed).Once()
+
+		err := Run("user", oldKey, newKey, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error {
+			t.Fatal("unexpected upload")
+			return nil
+		}, func(auth string) error {
+			t.Fatal("unexpected credential update")
+			return nil
+		})
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+		assert.Equal(t, StatePrepare, stateRepo.journal(t).State)
+
+		filesRepo.AssertExpectations(t)
+		filesRepo.AssertNotCalled(t, "ReplaceFile", mock.Anything, mock.Anything)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("resume skips staged files", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{State: StatePrepare, Files: []string{"a.bin", "b.bin"}}, stateRepo))
+
+		filesRepo.On("FindAll", ctx).Return(files("a.bin", "b.bin"), nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(true).Once()
+		stagingRepo.On("CheckKey", "a.bin", newKey).Return(nil).Once()
+		stagingRepo.On("Exists", "b.bin").Return(true).Once()
+		stagingRepo.On("CheckKey", "b.bin", newKey).Return(apperrors.ErrDecryptFailed).Once()
+		filesRepo.On("RekeyFile", "b.bin", "/staging/b.bin", oldKey, newKey).Return(nil).Once()
+		for _, name := range []string{"a.bin", "b.bin"} {
+			stagingRepo.On("Exists", name).Return(true).Once()
+			filesRepo.On("ReplaceFile", name, "/staging/"+name).Return(nil).Once()
+		}
+
+		var sent credentials
+		err := Run("user", oldKey, newKey, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error { return nil }, sent.update)
+		assert.NoError(t, err)
+		assert.Equal(t, credentials{"new auth"}, sent)
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("resume uploads without passwords", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{
+			State:    StateUpload,
+			Files:    []string{"a.bin", "b.bin"},
+			Uploaded: []string{"a.bin"},
+		}, stateRepo))
+
+		var uploaded []string
+		var sent credentials
+		err := Run("user", nil, nil, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error {
+			uploaded = append(uploaded, name)
+			return nil
+		}, sent.update)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b.bin"}, uploaded)
+		assert.Empty(t, sent)
+	})
+
+	t.Run("credential error keeps vault untouched", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		var uploaded []string
+		upload := func(name string) error {
+			uploaded = append(uploaded, name)
+			return nil
+		}
+
+		filesRepo.On("FindAll", ctx).Return(files("a.bin"), nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(false).Once()
+		filesRepo.On("RekeyFile", "a.bin", "/staging/a.bin", oldKey, newKey).Return(nil).Once()
+
+		// Сервер отклоняет новый ключ авторизации
+		err := Run("user", oldKey, newKey, filesRepo, stagingRepo, stateRepo, profileRepo, upload, func(auth string) error {
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, StateCredential, stateRepo.journal(t).State)
+		assert.Equal(t, "new auth", stateRepo.journal(t).Auth)
+		assert.Empty(t, uploaded)
+
+		// При запуске клиента копии не переносятся, пока сервер не принял ключ
+		require.NoError(t, Recover("user", filesRepo, stagingRepo, stateRepo, profileRepo))
+		filesRepo.AssertNotCalled(t, "ReplaceFile", mock.Anything, mock.Anything)
+		assert.Empty(t, profileRepo.files)
+
+		// Повторный запуск без паролей заменяет ключ и переносит копии
+		stagingRepo.On("Exists", "a.bin").Return(true).Once()
+		filesRepo.On("ReplaceFile", "a.bin", "/staging/a.bin").Return(nil).Once()
+		var sent credentials
+		require.NoError(t, Run("user", nil, nil, filesRepo, stagingRepo, stateRepo, profileRepo, upload, sent.update))
+		assert.Equal(t, credentials{"new auth"}, sent)
+		assert.Equal(t, []string{"a.bin"}, uploaded)
+		assert.Nil(t, stateRepo.journal(t))
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("key file change keeps credential", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		withKeyFile := &masterkey.Key{Password: "old", Auth: "old auth", Vault: []byte("key file vault")}
+
+		filesRepo.On("FindAll", ctx).Return(files("a.bin"), nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(false).Once()
+		filesRepo.On("RekeyFile", "a.bin", "/staging/a.bin", oldKey, withKeyFile).Return(nil).Once()
+		stagingRepo.On("Exists", "a.bin").Return(true).Once()
+		filesRepo.On("ReplaceFile", "a.bin", "/staging/a.bin").Return(nil).Once()
+
+		var sent credentials
+		err := Run("user", oldKey, withKeyFile, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error { return nil }, sent.update)
+		assert.NoError(t, err)
+		assert.Empty(t, sent)
+	})
+
+	t.Run("upload error is resumable", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{State: StateUpload, Files: []string{"a.bin", "b.bin"}}, stateRepo))
+
+		err := Run("user", nil, nil, filesRepo, stagingRepo, stateRepo, profileRepo, func(name string) error {
+			if name == "b.bin" {
+				return assert.AnError
+			}
+			return nil
+		}, func(auth string) error {
+			t.Fatal("unexpected credential update")
+			return nil
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+
+		journal := stateRepo.journal(t)
+		assert.Equal(t, StateUpload, journal.State)
+		assert.Equal(t, []string{"a.bin"}, journal.Uploaded)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		stateRepo := newMemoryStateRepository()
+		noUpload := func(name string) error { return nil }
+		noCredential := func(auth string) error { return nil }
+
+		err := Run("user", nil, newKey, nil, nil, stateRepo, nil, noUpload, noCredential)
+		assert.EqualError(t, err, "empty password")
+
+		err = Run("user", oldKey, nil, nil, nil, stateRepo, nil, noUpload, noCredential)
+		assert.EqualError(t, err, "empty new password")
+
+		err = Run("user", oldKey, &masterkey.Key{Password: "old", Vault: []byte("old vault key")}, nil, nil, stateRepo, nil, noUpload, noCredential)
+		assert.Error(t, err)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("finishes commit", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{State: StateCommit, Files: []string{"a.bin", "b.bin"}}, stateRepo))
+
+		// a.bin уже перенесён до остановки клиента
+		stagingRepo.On("Exists", "a.bin").Return(false).Once()
+		stagingRepo.On("Exists", "b.bin").Return(true).Once()
+		filesRepo.On("ReplaceFile", "b.bin", "/staging/b.bin").Return(nil).Once()
+
+		assert.NoError(t, Recover("user", filesRepo, stagingRepo, stateRepo, profileRepo))
+		assert.Equal(t, StateUpload, stateRepo.journal(t).State)
+		assert.Empty(t, profileRepo.files)
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+
+	t.Run("saves new KDF parameters", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{
+			State: StateCommit,
+			Files: []string{"a.bin"},
+			KDF:   &masterkey.Params{Time: 4, Memory: 8192, Threads: 1},
+		}, stateRepo))
+
+		stagingRepo.On("Exists", "a.bin").Return(true).Once()
+		filesRepo.On("ReplaceFile", "a.bin", "/staging/a.bin").Return(nil).Once()
+
+		assert.NoError(t, Recover("user", filesRepo, stagingRepo, stateRepo, profileRepo))
+		assert.JSONEq(t, `{"kdf":{"time":4,"memory":8192,"threads":1}}`, profileRepo.files["user.json"])
+	})
+
+	t.Run("nothing to recover", func(t *testing.T) {
+		filesRepo := new(MockFileRepository)
+		stagingRepo := new(MockStagingRepository)
+		stateRepo := newMemoryStateRepository()
+		profileRepo := newMemoryStateRepository()
+		require.NoError(t, saveJournal(ctx, "user", &Journal{State: StatePrepare, Files: []string{"a.bin"}}, stateRepo))
+
+		assert.NoError(t, Recover("user", filesRepo, stagingRepo, stateRepo, profileRepo))
+		assert.NoError(t, Recover("other", filesRepo, stagingRepo, stateRepo, profileRepo))
+		assert.Equal(t, StatePrepare, stateRepo.journal(t).State)
+
+		filesRepo.AssertExpectations(t)
+		stagingRepo.AssertExpectations(t)
+	})
+}
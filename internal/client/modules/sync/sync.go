@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 )
@@ -20,6 +21,10 @@ type TokenRepository interface {
 	GetFileContent(ctx context.Context, uuid string) (string, error)
 }
 
+type NameIndex interface {
+	Invalidate() error
+}
+
 type HTTPClient interface {
 	Get(endpoint string, queryParams map[string]string) ([]byte, error)
 	DownloadFile(fileURL, outputPath string) error
@@ -43,9 +48,67 @@ type Row struct {
 	Size        int    `json:"size"`
 	ContentType string `json:"content_type"`
 	Description string `json:"description"`
+	Tags        Tags   `json:"tags"`
+}
+
+// Tags метки объекта. Сервер возвращает их списком или строкой через
+// запятую, в том виде, в каком они были переданы при загрузке.
+type Tags []string
+
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = list
+		return nil
+	}
+	var joined string
+	if err := json.Unmarshal(data, &joined); err != nil {
+		return err
+	}
+	*t = nil
+	for _, tag := range strings.Split(joined, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// Filter ограничивает скачивание новых объектов категорией и метками.
+type Filter struct {
+	Category string
+	Tags     []string
+}
+
+func (f Filter) empty() bool {
+	return f.Category == "" && len(f.Tags) == 0
 }
 
-func Run(username string, fileRepo FileRepository, syncRepo TokenRepository, http HTTPClient) error {
+// match проверяет объект на стороне клиента: сервер, не поддерживающий
+// фильтр, вернёт все объекты. Объект без меток в ответе под фильтр
+// по меткам не подходит.
+func (f Filter) match(row Row) bool {
+	if f.Category != "" && row.Category != f.Category {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(row.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run скачивает новые объекты с сервера и удаляет локальные копии удалённых.
+// Объекты известны серверу только по идентификаторам, а имена записей
+// хранятся в их зашифрованных метаданных, поэтому после изменений локальный
+// индекс имён сбрасывается и восстанавливается при следующем обращении.
+//
+// С фильтром скачиваются только объекты с заданной категорией и всеми
+// метками, а удаления применяются все. Время синхронизации после
+// синхронизации с фильтром не сохраняется, чтобы следующая полная
+// синхронизация скачала пропущенные объекты.
+func Run(username string, filter Filter, fileRepo FileRepository, syncRepo TokenRepository, names NameIndex, http HTTPClient) error {
 
 	ctx := context.Background()
 	now := time.Now()
@@ -71,6 +134,7 @@ func Run(username string, fileRepo FileRepository, syncRepo TokenRepository, htt
 		return err
 	}
 
+	changed := false
 	for _, row := range deletedFiles {
 		if !fileRepo.Exists(row.Name) {
 			continue
@@ -79,10 +143,17 @@ func Run(username string, fileRepo FileRepository, syncRepo TokenRepository, htt
 		if err != nil {
 			return err
 		}
+		changed = true
 	}
 
 	// download new files
 	params["deleted"] = "false"
+	if filter.Category != "" {
+		params["category"] = filter.Category
+	}
+	if len(filter.Tags) > 0 {
+		params["tags"] = strings.Join(filter.Tags, ",")
+	}
 	newFilesResponse, err := http.Get("/uploads", params)
 	if err != nil {
 		return err
@@ -96,6 +167,9 @@ func Run(username string, fileRepo FileRepository, syncRepo TokenRepository, htt
 		if fileRepo.Exists(row.Name) {
 			continue
 		}
+		if !filter.match(row) {
+			continue
+		}
 		url := "/file?name=" + row.Name
 		filepath := fileRepo.GetPath(row.Name)
 
@@ -103,6 +177,17 @@ func Run(username string, fileRepo FileRepository, syncRepo TokenRepository, htt
 		if err != nil {
 			return err
 		}
+		changed = true
+	}
+
+	if changed {
+		if err := names.Invalidate(); err != nil {
+			return err
+		}
+	}
+
+	if !filter.empty() {
+		return nil
 	}
 
 	err = syncRepo.Save(ctx, username, strings.NewReader(now.Format("2006-01-02")))
@@ -46,6 +46,15 @@ func (m *MockTokenRepository) GetFileContent(ctx context.Context, uuid string) (
 	return args.String(0), args.Error(1)
 }
 
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Invalidate() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 type MockHTTPClient struct {
 	mock.Mock
 }
@@ -78,6 +87,7 @@ func TestRun(t *testing.T) {
 		getDeletedErr error
 		getNewErr     error
 		saveSyncErr   error
+		invalidate    bool
 		wantErr       bool
 	}{
 		{
@@ -87,6 +97,16 @@ func TestRun(t *testing.T) {
 			newFiles:      []Row{{Name: "file3.txt"}, {Name: "file4.txt"}},
 			deletedExists: []bool{true, false},
 			newExists:     []bool{false, true},
+			invalidate:    true,
+			wantErr:       false,
+		},
+		{
+			name:          "nothing changed",
+			prevSyncTime:  lastSyncTime.Format(time.RFC3339),
+			deletedFiles:  []Row{{Name: "file1.txt"}},
+			newFiles:      []Row{{Name: "file2.txt"}},
+			deletedExists: []bool{false},
+			newExists:     []bool{true},
 			wantErr:       false,
 		},
 		{
@@ -135,6 +155,10 @@ func TestRun(t *testing.T) {
 			mockFileRepo := new(MockFileRepository)
 			mockTokenRepo := new(MockTokenRepository)
 			mockHTTP := new(MockHTTPClient)
+			mockIndex := new(MockNameIndex)
+			if tt.invalidate {
+				mockIndex.On("Invalidate").Return(nil).Once()
+			}
 
 			// Setup expectations for getting previous sync time
 			mockTokenRepo.On("GetFileContent", ctx, username).Return(tt.prevSyncTime, tt.getSyncErr).Once()
@@ -203,7 +227,7 @@ func TestRun(t *testing.T) {
 				}
 			}
 
-			err := Run(username, mockFileRepo, mockTokenRepo, mockHTTP)
+			err := Run(username, Filter{}, mockFileRepo, mockTokenRepo, mockIndex, mockHTTP)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -213,10 +237,65 @@ func TestRun(t *testing.T) {
 			mockFileRepo.AssertExpectations(t)
 			mockTokenRepo.AssertExpectations(t)
 			mockHTTP.AssertExpectations(t)
+			mockIndex.AssertExpectations(t)
 		})
 	}
 }
 
+func TestRunFilter(t *testing.T) {
+	ctx := context.Background()
+	username := "testuser"
+	lastSync := time.Now().AddDate(0, -1, 0)
+	uploadedAt := lastSync.Format("2006-01-02")
+
+	mockFileRepo := new(MockFileRepository)
+	mockTokenRepo := new(MockTokenRepository)
+	mockHTTP := new(MockHTTPClient)
+	mockIndex := new(MockNameIndex)
+
+	mockTokenRepo.On("GetFileContent", ctx, username).Return(lastSync.Format(time.RFC3339), nil).Once()
+
+	// Удаления применяются без фильтра
+	deletedJSON, _ := json.Marshal(Response{Rows: []Row{{Name: "old", Category: "card"}}})
+	mockHTTP.On("Get", "/uploads", map[string]string{
+		"deleted":     "true",
+		"uploaded_at": uploadedAt,
+	}).Return(deletedJSON, nil).Once()
+	mockFileRepo.On("Exists", "old").Return(true).Once()
+	mockFileRepo.On("Delete", ctx, "old").Return(nil).Once()
+
+	// Объекты другой категории или без всех меток не скачиваются, даже если
+	// сервер их вернул
+	newJSON, _ := json.Marshal(Response{Rows: []Row{
+		{Name: "a", Category: "work", Tags: Tags{"tax", "2024", "q1"}},
+		{Name: "b", Category: "home", Tags: Tags{"2024", "tax"}},
+		{Name: "c", Category: "work", Tags: Tags{"2024"}},
+		{Name: "d", Category: "work"},
+	}})
+	mockHTTP.On("Get", "/uploads", map[string]string{
+		"deleted":     "false",
+		"uploaded_at": uploadedAt,
+		"category":    "work",
+		"tags":        "2024,tax",
+	}).Return(newJSON, nil).Once()
+	mockFileRepo.On("Exists", "a").Return(false).Once()
+	mockFileRepo.On("Exists", "b").Return(false).Once()
+	mockFileRepo.On("Exists", "c").Return(false).Once()
+	mockFileRepo.On("Exists", "d").Return(false).Once()
+	mockFileRepo.On("GetPath", "a").Return("/path/to/a").Once()
+	mockHTTP.On("DownloadFile", "/file?name=a", "/path/to/a").Return(nil).Once()
+	mockIndex.On("Invalidate").Return(nil).Once()
+
+	err := Run(username, Filter{Category: "work", Tags: []string{"2024", "tax"}}, mockFileRepo, mockTokenRepo, mockIndex, mockHTTP)
+	assert.NoError(t, err)
+
+	// Время синхронизации с фильтром не сохраняется
+	mockTokenRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	mockFileRepo.AssertExpectations(t)
+	mockHTTP.AssertExpectations(t)
+	mockIndex.AssertExpectations(t)
+}
+
 func TestExtractRows(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -239,6 +318,22 @@ func TestExtractRows(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "tags as list or string",
+			input: []byte(`{
+				"rows": [
+					{"uuid": "1", "name": "file1.txt", "tags": ["2024", "tax"]},
+					{"uuid": "2", "name": "file2.txt", "tags": "2024, tax"},
+					{"uuid": "3", "name": "file3.txt", "tags": ""}
+				]
+			}`),
+			want: []Row{
+				{UUID: "1", Name: "file1.txt", Tags: Tags{"2024", "tax"}},
+				{UUID: "2", Name: "file2.txt", Tags: Tags{"2024", "tax"}},
+				{UUID: "3", Name: "file3.txt"},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "invalid json",
 			input:   []byte(`invalid`),
@@ -0,0 +1,239 @@
+// Package totp хранит секреты двухфакторной аутентификации и вычисляет
+// одноразовые коды по RFC 6238. Секрет шифруется в памяти и хранится так же,
+// как остальные записи.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/jsonrecord"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Category категория записи TOTP по умолчанию.
+const Category = "totp"
+
+// Параметры по умолчанию из RFC 6238 и формата otpauth://
+const (
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
+type FileRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+	ReadRecord(name string, key *masterkey.Key) ([]byte, error)
+}
+
+type NameIndex interface {
+	Assign(name string, key *masterkey.Key) (string, error)
+	Resolve(name string, key *masterkey.Key) (string, error)
+}
+
+type TOTPJSON struct {
+	Secret    string `json:"secret"` // Секрет в base32 без разделителей и дополнения
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+}
+
+// RecordName возвращает имя записи TOTP в локальном индексе.
+func RecordName(Name string) string {
+	return "totp_" + Name
+}
+
+// New проверяет параметры и возвращает запись TOTP. Нулевые и пустые
+// параметры заменяются значениями по умолчанию.
+func New(Secret string, Algorithm string, Digits int, Period int) (*TOTPJSON, error) {
+	t := &TOTPJSON{
+		Secret:    strings.ToUpper(strings.TrimRight(strings.Join(strings.Fields(Secret), ""), "=")),
+		Algorithm: strings.ToUpper(Algorithm),
+		Digits:    Digits,
+		Period:    Period,
+	}
+	if t.Algorithm == "" {
+		t.Algorithm = DefaultAlgorithm
+	}
+	if t.Digits == 0 {
+		t.Digits = DefaultDigits
+	}
+	if t.Period == 0 {
+		t.Period = DefaultPeriod
+	}
+	return t, t.validate()
+}
+
+// ParseURI разбирает ссылку otpauth://totp/Issuer:account?secret=...,
+// которую показывают сервисы в QR-коде при включении 2FA.
+func ParseURI(uri string) (*TOTPJSON, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("неверная ссылка otpauth: %w", err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, errors.New("ссылка должна начинаться с otpauth://")
+	}
+	if u.Host != "totp" {
+		return nil, fmt.Errorf("неподдерживаемый тип кодов %q, поддерживается только totp", u.Host)
+	}
+
+	q := u.Query()
+	digits, period := 0, 0
+	if v := q.Get("digits"); v != "" {
+		if digits, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("неверное число цифр %q", v)
+		}
+	}
+	if v := q.Get("period"); v != "" {
+		if period, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("неверный период %q", v)
+		}
+	}
+	t, err := New(q.Get("secret"), q.Get("algorithm"), digits, period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Метка имеет вид "Issuer:account" или "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		t.Issuer, t.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		t.Account = label
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		t.Issuer = issuer
+	}
+	return t, nil
+}
+
+// Code возвращает код для момента now и время до смены кода.
+func (t *TOTPJSON) Code(now time.Time) (string, time.Duration, error) {
+	if err := t.validate(); err != nil {
+		return "", 0, err
+	}
+	secret, _ := decodeSecret(t.Secret)
+	defer clear(secret)
+
+	period := int64(t.Period)
+	unix := now.Unix()
+	counter := unix / period
+	remaining := time.Duration(period-unix%period) * time.Second
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(hashes[t.Algorithm], secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение из RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range t.Digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%mod), remaining, nil
+}
+
+// Save шифрует запись TOTP в памяти и возвращает идентификатор объекта.
+// Запись с тем же именем заменяется.
+func Save(Name string, t *TOTPJSON, Labels entities.Labels, Key *masterkey.Key, repo FileRepository, names NameIndex) (string, error) {
+	if Key == nil {
+		return "", errors.New("empty password")
+	}
+	if Name == "" {
+		return "", errors.New("empty totp name")
+	}
+	if err := t.validate(); err != nil {
+		return "", err
+	}
+
+	name := RecordName(Name)
+	filename, err := names.Assign(name, Key)
+	if err != nil {
+		return "", err
+	}
+
+	err = jsonrecord.Write(repo, filename, t, Key, entities.Metadata{
+		Alias:  name,
+		Labels: Labels.OrCategory(Category),
+	})
+	if err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// Get находит запись TOTP по имени и расшифровывает её в памяти.
+func Get(Name string, Key *masterkey.Key, repo FileRepository, names NameIndex) (*TOTPJSON, error) {
+	if Key == nil {
+		return nil, errors.New("empty password")
+	}
+	if Name == "" {
+		return nil, errors.New("empty totp name")
+	}
+
+	filename, err := names.Resolve(RecordName(Name), Key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := repo.ReadRecord(filename, Key)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+
+	var t TOTPJSON
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal totp: %v", err)
+	}
+	return &t, nil
+}
+
+var hashes = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+func (t *TOTPJSON) validate() error {
+	if t.Secret == "" {
+		return errors.New("empty totp secret")
+	}
+	secret, err := decodeSecret(t.Secret)
+	if err != nil {
+		return fmt.Errorf("секрет должен быть в кодировке base32: %w", err)
+	}
+	clear(secret)
+	if _, ok := hashes[t.Algorithm]; !ok {
+		return fmt.Errorf("неподдерживаемый алгоритм %q, допустимы SHA1, SHA256, SHA512", t.Algorithm)
+	}
+	if t.Digits < 6 || t.Digits > 8 {
+		return fmt.Errorf("число цифр кода должно быть от 6 до 8, задано %d", t.Digits)
+	}
+	if t.Period <= 0 {
+		return fmt.Errorf("период должен быть положительным, задан %d", t.Period)
+	}
+	return nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+}
@@ -0,0 +1,202 @@
+package totp
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	// Данные затираются после вызова, поэтому сохраняем копию
+	args := m.Called(name, string(data), key, meta)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ReadRecord(name string, key *masterkey.Key) ([]byte, error) {
+	args := m.Called(name, key)
+	data, _ := args.Get(0).([]byte)
+	return data, args.Error(1)
+}
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) Assign(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNameIndex) Resolve(name string, key *masterkey.Key) (string, error) {
+	args := m.Called(name, key)
+	return args.String(0), args.Error(1)
+}
+
+func encode(secret string) string {
+	return base32.StdEncoding.EncodeToString([]byte(secret))
+}
+
+// Контрольные значения из приложения B RFC 6238
+func TestCode(t *testing.T) {
+	secrets := map[string]string{
+		"SHA1":   encode("12345678901234567890"),
+		"SHA256": encode("12345678901234567890123456789012"),
+		"SHA512": encode("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		code      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1234567890, "SHA1", "89005924"},
+		{2000000000, "SHA256", "90698825"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"/"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			totp, err := New(secrets[tt.algorithm], tt.algorithm, 8, 30)
+			require.NoError(t, err)
+
+			code, remaining, err := totp.Code(time.Unix(tt.unix, 0))
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, time.Duration(30-tt.unix%30)*time.Second, remaining)
+		})
+	}
+
+	t.Run("six digits", func(t *testing.T) {
+		totp, err := New(secrets["SHA1"], "", 0, 0)
+		require.NoError(t, err)
+		code, remaining, err := totp.Code(time.Unix(59, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "287082", code)
+		assert.Equal(t, time.Second, remaining)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("normalizes secret", func(t *testing.T) {
+		totp, err := New("jbsw y3dp ehpk 3pxp==", "sha256", 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, &TOTPJSON{Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA256", Digits: 6, Period: 30}, totp)
+	})
+
+	tests := []struct {
+		name      string
+		secret    string
+		algorithm string
+		digits    int
+		period    int
+	}{
+		{"empty secret", "", "", 0, 0},
+		{"not base32", "not-base32!", "", 0, 0},
+		{"unknown algorithm", "JBSWY3DPEHPK3PXP", "MD5", 0, 0},
+		{"too few digits", "JBSWY3DPEHPK3PXP", "", 4, 0},
+		{"too many digits", "JBSWY3DPEHPK3PXP", "", 10, 0},
+		{"negative period", "JBSWY3DPEHPK3PXP", "", 0, -30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.secret, tt.algorithm, tt.digits, tt.period)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	t.Run("full", func(t *testing.T) {
+		totp, err := ParseURI("otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&algorithm=SHA256&digits=8&period=60")
+		require.NoError(t, err)
+		assert.Equal(t, &TOTPJSON{
+			Secret:    "JBSWY3DPEHPK3PXP",
+			Algorithm: "SHA256",
+			Digits:    8,
+			Period:    60,
+			Issuer:    "Example",
+			Account:   "alice@example.com",
+		}, totp)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		totp, err := ParseURI("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		assert.Equal(t, &TOTPJSON{Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA1", Digits: 6, Period: 30, Account: "alice"}, totp)
+	})
+
+	for _, uri := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/alice",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=0x1e",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			_, err := ParseURI(uri)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+	totp, err := New("JBSWY3DPEHPK3PXP", "", 0, 0)
+	require.NoError(t, err)
+	data, err := json.Marshal(totp)
+	require.NoError(t, err)
+
+	repo := new(MockFileRepository)
+	names := new(MockNameIndex)
+	names.On("Assign", "totp_github", key).Return("5a4b3c", nil)
+	meta := entities.Metadata{Alias: "totp_github", MIMEType: "application/json", Labels: entities.Labels{Category: Category}}
+	repo.On("WriteRecord", "5a4b3c", string(data), key, meta).Return(nil)
+
+	id, err := Save("github", totp, entities.Labels{}, key, repo, names)
+	require.NoError(t, err)
+	assert.Equal(t, "5a4b3c", id)
+	repo.AssertExpectations(t)
+
+	_, err = Save("", totp, entities.Labels{}, key, repo, names)
+	assert.Error(t, err)
+}
+
+func TestGet(t *testing.T) {
+	key := &masterkey.Key{Password: "secure"}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(MockFileRepository)
+		names := new(MockNameIndex)
+		names.On("Resolve", "totp_github", key).Return("5a4b3c", nil)
+		repo.On("ReadRecord", "5a4b3c", key).Return([]byte(`{"secret":"JBSWY3DPEHPK3PXP","algorithm":"SHA1","digits":6,"period":30}`), nil)
+
+		totp, err := Get("github", key, repo, names)
+		require.NoError(t, err)
+		assert.Equal(t, &TOTPJSON{Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA1", Digits: 6, Period: 30}, totp)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		names := new(MockNameIndex)
+		names.On("Resolve", "totp_missing", key).Return("", apperrors.ErrFileNotFound)
+
+		_, err := Get("missing", key, new(MockFileRepository), names)
+		assert.ErrorIs(t, err, apperrors.ErrFileNotFound)
+	})
+}
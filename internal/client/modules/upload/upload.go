@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
 )
 
 type FileRepository interface {
@@ -18,18 +21,16 @@ type UploadResponse struct {
 	UUID string `json:"uuid"`
 }
 
-func Run(repo FileRepository, filename string, category string, http HTTPClient) error {
+// Run отправляет зашифрованный файл на сервер вместе с описанием, категорией
+// и метками, по которым работают фильтры синхронизации. Имя, тип и другие
+// сведения о файле хранятся в его зашифрованных метаданных и не передаются.
+func Run(repo FileRepository, filename string, labels entities.Labels, http HTTPClient) error {
 
 	ctx := context.Background()
 
 	filepath := repo.GetPath(filename)
 
-	postData := map[string]string{
-		"description": "ololo alala",
-		"category":    category,
-	}
-
-	responce, err := http.UploadFile(ctx, "/upload", filepath, postData)
+	responce, err := http.UploadFile(ctx, "/upload", filepath, FormFields(labels))
 	if err != nil {
 		return err
 	}
@@ -44,6 +45,22 @@ func Run(repo FileRepository, filename string, category string, http HTTPClient)
 	return nil
 }
 
+// FormFields возвращает заданные метки записи в виде полей формы загрузки.
+// Метки передаются одним полем через запятую.
+func FormFields(labels entities.Labels) map[string]string {
+	fields := make(map[string]string)
+	if labels.Description != "" {
+		fields["description"] = labels.Description
+	}
+	if labels.Category != "" {
+		fields["category"] = labels.Category
+	}
+	if len(labels.Tags) > 0 {
+		fields["tags"] = strings.Join(labels.Tags, ",")
+	}
+	return fields
+}
+
 func ExtractUUID(responseBytes []byte) (string, error) {
 	var uploadResp UploadResponse
 	if err := json.Unmarshal(responseBytes, &uploadResp); err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/aube/keeper/internal/client/entities"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -34,23 +35,32 @@ func TestRun(t *testing.T) {
 	tests := []struct {
 		name      string
 		filename  string
-		category  string
 		path      string
+		labels    entities.Labels
+		fields    map[string]string
 		uploadErr error
 		wantErr   bool
 	}{
 		{
 			name:     "success",
 			filename: "test.txt",
-			category: "docs",
 			path:     "/path/to/test.txt",
+			fields:   map[string]string{},
+			wantErr:  false,
+		},
+		{
+			name:     "labels",
+			filename: "test.txt",
+			path:     "/path/to/test.txt",
+			labels:   entities.Labels{Description: "отчёт", Category: "work", Tags: []string{"2024", "tax"}},
+			fields:   map[string]string{"description": "отчёт", "category": "work", "tags": "2024,tax"},
 			wantErr:  false,
 		},
 		{
 			name:      "upload error",
 			filename:  "test.txt",
-			category:  "docs",
 			path:      "/path/to/test.txt",
+			fields:    map[string]string{},
 			uploadErr: assert.AnError,
 			wantErr:   true,
 		},
@@ -58,15 +68,11 @@ func TestRun(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			expectedFields := map[string]string{
-				"description": "ololo alala",
-				"category":    tt.category,
-			}
-
+			// Серверу передаются только заданные пользователем метки
 			mockRepo.On("GetPath", tt.filename).Return(tt.path).Once()
-			mockHTTP.On("UploadFile", ctx, "/upload", tt.path, expectedFields).Return([]byte(`{"uuid":"123"}`), tt.uploadErr).Once()
+			mockHTTP.On("UploadFile", ctx, "/upload", tt.path, tt.fields).Return([]byte(`{"uuid":"123"}`), tt.uploadErr).Once()
 
-			err := Run(mockRepo, tt.filename, tt.category, mockHTTP)
+			err := Run(mockRepo, tt.filename, tt.labels, mockHTTP)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
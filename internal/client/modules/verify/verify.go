@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Status результат проверки одного файла.
+type Status string
+
+const (
+	StatusOK       Status = "ok"        // Файл цел
+	StatusLegacy   Status = "legacy"    // Файл цел, но записан в прежнем формате
+	StatusWrongKey Status = "wrong_key" // Ключ файла не расшифровывается паролем
+	StatusCorrupt  Status = "corrupt"   // Файл повреждён или подменён
+)
+
+// statusLabels подписи результатов в текстовом отчёте.
+var statusLabels = map[Status]string{
+	StatusOK:       "в порядке",
+	StatusLegacy:   "прежний формат",
+	StatusWrongKey: "неверный ключ",
+	StatusCorrupt:  "повреждён",
+}
+
+type FileRepository interface {
+	FindAll(ctx context.Context) (*entities.Files, error)
+	VerifyFile(filename string, key *masterkey.Key) (bool, error)
+}
+
+type NameIndex interface {
+	List(key *masterkey.Key) (map[string]string, error)
+}
+
+// Result результат проверки файла.
+type Result struct {
+	File   string `json:"file"`           // Имя файла в хранилище
+	Name   string `json:"name,omitempty"` // Имя записи из индекса
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report отчёт о проверке хранилища.
+type Report struct {
+	Files   []Result       `json:"files"`
+	Summary map[Status]int `json:"summary"`
+}
+
+// Failed возвращает количество файлов, которые не удалось расшифровать.
+func (r *Report) Failed() int {
+	return r.Summary[StatusWrongKey] + r.Summary[StatusCorrupt]
+}
+
+// Run проверяет подлинность каждого файла хранилища, не записывая открытый
+// текст. Имена записей берутся из индекса, если его удаётся прочитать.
+func Run(key *masterkey.Key, repo FileRepository, names NameIndex) (*Report, error) {
+	if key == nil {
+		return nil, errors.New("empty password")
+	}
+
+	files, err := repo.FindAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	// Отчёт нужен и тогда, когда индекс не читается, например при неверном пароле
+	byID := make(map[string]string)
+	if entries, err := names.List(key); err == nil {
+		for name, id := range entries {
+			byID[id] = name
+		}
+	}
+
+	report := &Report{
+		Files:   []Result{},
+		Summary: map[Status]int{StatusOK: 0, StatusLegacy: 0, StatusWrongKey: 0, StatusCorrupt: 0},
+	}
+	for _, file := range *files {
+		result := Result{File: file.Name, Name: byID[file.Name], Status: StatusOK}
+
+		legacy, err := repo.VerifyFile(file.Name, key)
+		switch {
+		case errors.Is(err, apperrors.ErrWrongPassword):
+			result.Status = StatusWrongKey
+		case err != nil:
+			result.Status = StatusCorrupt
+		case legacy:
+			result.Status = StatusLegacy
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		report.Files = append(report.Files, result)
+		report.Summary[result.Status]++
+	}
+
+	return report, nil
+}
+
+// Format возвращает отчёт в виде текста или JSON.
+func Format(report *Report, asJSON bool) (string, error) {
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	var b strings.Builder
+	for _, result := range report.Files {
+		fmt.Fprintf(&b, "%-15s %s", statusLabels[result.Status], result.File)
+		if result.Name != "" {
+			fmt.Fprintf(&b, " (%s)", result.Name)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(&b, ": %s", result.Error)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Проверено файлов: %d, в порядке: %d, в прежнем формате: %d, неверный ключ: %d, повреждено: %d",
+		len(report.Files),
+		report.Summary[StatusOK],
+		report.Summary[StatusLegacy],
+		report.Summary[StatusWrongKey],
+		report.Summary[StatusCorrupt],
+	)
+
+	return b.String(), nil
+}
@@ -0,0 +1,122 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) FindAll(ctx context.Context) (*entities.Files, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*entities.Files), args.Error(1)
+}
+
+func (m *MockFileRepository) VerifyFile(filename string, key *masterkey.Key) (bool, error) {
+	args := m.Called(filename, key)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockNameIndex struct {
+	mock.Mock
+}
+
+func (m *MockNameIndex) List(key *masterkey.Key) (map[string]string, error) {
+	args := m.Called(key)
+	entries, _ := args.Get(0).(map[string]string)
+	return entries, args.Error(1)
+}
+
+var testKey = &masterkey.Key{Password: "pass", Vault: []byte("vault")}
+
+func files(names ...string) *entities.Files {
+	result := entities.Files{}
+	for _, name := range names {
+		result = append(result, *entities.NewFile(name, "/files/"+name, 1))
+	}
+	return &result
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("statuses", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+
+		mockRepo.On("FindAll", ctx).Return(files("a1", "b2", "c3", "d4"), nil).Once()
+		mockIndex.On("List", testKey).Return(map[string]string{"report.txt": "a1"}, nil).Once()
+		mockRepo.On("VerifyFile", "a1", testKey).Return(false, nil).Once()
+		mockRepo.On("VerifyFile", "b2", testKey).Return(true, nil).Once()
+		mockRepo.On("VerifyFile", "c3", testKey).Return(false, fmt.Errorf("%w: ключ", apperrors.ErrWrongPassword)).Once()
+		mockRepo.On("VerifyFile", "d4", testKey).Return(false, fmt.Errorf("%w: блок 3", apperrors.ErrDecryptFailed)).Once()
+
+		report, err := Run(testKey, mockRepo, mockIndex)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Result{
+			{File: "a1", Name: "report.txt", Status: StatusOK},
+			{File: "b2", Status: StatusLegacy},
+			{File: "c3", Status: StatusWrongKey, Error: "wrong password: ключ"},
+			{File: "d4", Status: StatusCorrupt, Error: "wrong password or corrupted file: блок 3"},
+		}, report.Files)
+		assert.Equal(t, map[Status]int{StatusOK: 1, StatusLegacy: 1, StatusWrongKey: 1, StatusCorrupt: 1}, report.Summary)
+		assert.Equal(t, 2, report.Failed())
+
+		mockRepo.AssertExpectations(t)
+		mockIndex.AssertExpectations(t)
+	})
+
+	t.Run("unreadable index", func(t *testing.T) {
+		mockRepo := new(MockFileRepository)
+		mockIndex := new(MockNameIndex)
+
+		mockRepo.On("FindAll", ctx).Return(files("a1"), nil).Once()
+		mockIndex.On("List", testKey).Return(nil, apperrors.ErrWrongPassword).Once()
+		mockRepo.On("VerifyFile", "a1", testKey).Return(false, apperrors.ErrWrongPassword).Once()
+
+		report, err := Run(testKey, mockRepo, mockIndex)
+		require.NoError(t, err)
+		assert.Equal(t, StatusWrongKey, report.Files[0].Status)
+		assert.Empty(t, report.Files[0].Name)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := Run(nil, nil, nil)
+		assert.EqualError(t, err, "empty password")
+	})
+}
+
+func TestFormat(t *testing.T) {
+	report := &Report{
+		Files: []Result{
+			{File: "a1", Name: "report.txt", Status: StatusOK},
+			{File: "d4", Status: StatusCorrupt, Error: "блок 3"},
+		},
+		Summary: map[Status]int{StatusOK: 1, StatusLegacy: 0, StatusWrongKey: 0, StatusCorrupt: 1},
+	}
+
+	text, err := Format(report, false)
+	require.NoError(t, err)
+	assert.Contains(t, text, "в порядке       a1 (report.txt)\n")
+	assert.Contains(t, text, "повреждён       d4: блок 3\n")
+	assert.Contains(t, text, "Проверено файлов: 2, в порядке: 1, в прежнем формате: 0, неверный ключ: 0, повреждено: 1")
+
+	data, err := Format(report, true)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(data), &decoded))
+	assert.Equal(t, map[string]any{"ok": 1.0, "legacy": 0.0, "wrong_key": 0.0, "corrupt": 1.0}, decoded["summary"])
+	assert.Equal(t, "corrupt", decoded["files"].([]any)[1].(map[string]any)["status"])
+}
@@ -2,7 +2,42 @@ package apperrors
 
 import (
 	"errors"
+	"maps"
+	"slices"
+	"strings"
 )
 
 var ErrFileNotFound = errors.New("file not found")
 var ErrTokenNotFound = errors.New("token not found")
+var ErrUnsupportedFormat = errors.New("unsupported encrypted file format")
+var ErrDecryptFailed = errors.New("wrong password or corrupted file")
+var ErrKeyFileNotFound = errors.New("key file not found")
+var ErrInsecureStorage = errors.New("storage permissions are too loose")
+var ErrNotImplemented = errors.New("not implemented")
+var ErrKDFParamsChanged = errors.New("KDF parameters differ from the saved ones")
+var ErrFileExists = errors.New("file already exists")
+
+// ErrWrongPassword ключ файла не расшифровывается паролем. Частный случай
+// ErrDecryptFailed: errors.Is(err, ErrDecryptFailed) для неё тоже истинно.
+var ErrWrongPassword error = &kindError{msg: "wrong password", kind: ErrDecryptFailed}
+
+// kindError ошибка, уточняющая более общую ошибку kind.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// FieldErrors ошибки проверки полей записи: имя поля — описание ошибки.
+// Интерфейс показывает их рядом с полями ввода.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, field := range slices.Sorted(maps.Keys(e)) {
+		msgs = append(msgs, field+": "+e[field])
+	}
+	return strings.Join(msgs, "; ")
+}
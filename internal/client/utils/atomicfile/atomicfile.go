@@ -0,0 +1,102 @@
+// Package atomicfile записывает файлы так, что после сбоя или ошибки
+// на месте файла остаётся либо прежнее содержимое, либо новое целиком.
+//
+// Данные пишутся во временный файл в том же каталоге, сбрасываются на диск
+// и переименовываются в итоговый путь:
+//
+//	f, err := atomicfile.Create(path, 0600)
+//	defer f.Abort()
+//	io.Copy(f, src)
+//	return f.Commit()
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempSuffix окончание имени временного файла.
+const tempSuffix = ".tmp"
+
+// File временный файл, который Commit переименовывает в итоговый путь.
+type File struct {
+	*os.File
+	path string
+	done bool
+}
+
+// Create создаёт временный файл рядом с path с правами perm.
+func Create(path string, perm os.FileMode) (*File, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".*"+tempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &File{File: f, path: path}, nil
+}
+
+// Commit сбрасывает данные на диск и заменяет ими итоговый файл.
+func (f *File) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	if err := f.Sync(); err != nil {
+		f.discard()
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Abort удаляет временный файл, если Commit не был вызван. Вызывается
+// через defer сразу после Create.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.discard()
+}
+
+func (f *File) discard() {
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// IsTemp сообщает, что name — имя временного файла, оставшегося после
+// сбоя. Такие файлы не считаются записанными.
+func IsTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, tempSuffix)
+}
+
+// syncDir сбрасывает на диск запись каталога, чтобы переименование
+// пережило сбой питания. Не все системы позволяют это, поэтому ошибки
+// не возвращаются.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
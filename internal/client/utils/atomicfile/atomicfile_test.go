@@ -0,0 +1,73 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+
+	t.Run("abort keeps old content", func(t *testing.T) {
+		f, err := Create(path, 0600)
+		require.NoError(t, err)
+		_, err = f.WriteString("partial")
+		require.NoError(t, err)
+		f.Abort()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+		assertNoTemp(t, dir)
+	})
+
+	t.Run("commit replaces content", func(t *testing.T) {
+		f, err := Create(path, 0600)
+		require.NoError(t, err)
+		defer f.Abort()
+
+		// До Commit итоговый файл не меняется, временный скрыт
+		_, err = f.WriteString("new")
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+		assert.True(t, IsTemp(filepath.Base(f.Name())))
+
+		require.NoError(t, f.Commit())
+		data, err = os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+		assertNoTemp(t, dir)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := Create(filepath.Join(dir, "missing", "data.txt"), 0600)
+		assert.Error(t, err)
+	})
+}
+
+func TestIsTemp(t *testing.T) {
+	assert.True(t, IsTemp(".data.txt.123.tmp"))
+	assert.False(t, IsTemp("data.txt"))
+	assert.False(t, IsTemp("9a8b7c"))
+}
+
+func assertNoTemp(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, IsTemp(e.Name()), e.Name())
+	}
+}
@@ -0,0 +1,80 @@
+package cryptostream
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Названия шифров, которые можно выбрать в конфигурации.
+const (
+	CipherAES256GCM         = "aes-256-gcm"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+// Идентификаторы шифров в заголовке контейнера. Значения не меняются,
+// новые шифры получают следующий свободный идентификатор.
+const (
+	cipherIDAES256GCM         uint8 = 1
+	cipherIDXChaCha20Poly1305 uint8 = 2
+)
+
+// cipherSuite описывает AEAD-шифр, которым может быть зашифрован файл.
+type cipherSuite struct {
+	id      uint8
+	name    string
+	newAEAD func(key []byte) (cipher.AEAD, error)
+}
+
+var cipherSuites = []cipherSuite{
+	{id: cipherIDAES256GCM, name: CipherAES256GCM, newAEAD: newGCM},
+	{id: cipherIDXChaCha20Poly1305, name: CipherXChaCha20Poly1305, newAEAD: newXChaCha20Poly1305},
+}
+
+// cipherByName ищет шифр по названию из конфигурации.
+func cipherByName(name string) (cipherSuite, error) {
+	for _, s := range cipherSuites {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	return cipherSuite{}, fmt.Errorf("неизвестный шифр %q", name)
+}
+
+// cipherByID ищет шифр по идентификатору из заголовка.
+func cipherByID(id uint8) (cipherSuite, error) {
+	for _, s := range cipherSuites {
+		if s.id == id {
+			return s, nil
+		}
+	}
+	return cipherSuite{}, fmt.Errorf("%w: шифр %d", apperrors.ErrUnsupportedFormat, id)
+}
+
+// newGCM создает AES-GCM для заданного ключа.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания блока шифра: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания GCM: %w", err)
+	}
+
+	return gcm, nil
+}
+
+// newXChaCha20Poly1305 создает XChaCha20-Poly1305 для заданного ключа.
+// Шифр не требует аппаратной поддержки AES и быстр на любых процессорах.
+func newXChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания XChaCha20-Poly1305: %w", err)
+	}
+	return aead, nil
+}
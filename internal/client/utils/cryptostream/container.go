@@ -0,0 +1,292 @@
+package cryptostream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// Заголовок контейнера описывает, как был зашифрован файл, поэтому параметры
+// шифрования можно менять, не теряя возможности прочитать старые файлы.
+//
+// Каждый файл шифруется собственным случайным ключом данных. Ключ данных
+// хранится в заголовке, зашифрованный ключом, полученным из ключа хранилища
+// (envelope encryption): при смене пароля перешифровывается только этот ключ,
+// а утечка ключа одного файла не раскрывает остальные.
+//
+// Формат заголовка (все числа big-endian):
+//
+//	magic      [4]byte   "KEEP"
+//	version    uint8     версия формата контейнера
+//	cipher     uint8     идентификатор шифра (см. cipher.go)
+//	chunkSize  uint32    размер блока открытого текста
+//	prefixLen  uint8
+//	prefix     [prefixLen]byte  случайный префикс nonce блоков (см. stream.go)
+//	-- блок ключа --
+//	kdf        uint8     идентификатор функции получения ключа (см. ниже)
+//	time       uint32    параметры Argon2id, нули для ключа хранилища
+//	memory     uint32
+//	threads    uint8
+//	saltLen    uint8
+//	salt       [saltLen]byte
+//	nonceLen   uint8
+//	nonce      [nonceLen]byte   nonce, которым зашифрован ключ данных
+//	wrappedLen uint8
+//	wrapped    [wrappedLen]byte зашифрованный ключ данных
+//	-- метаданные (с версии 4) --
+//	metaLen    uint32
+//	meta       [metaLen]byte    метаданные, зашифрованные ключом данных
+//
+// Байты заголовка до блока ключа передаются в AEAD как дополнительные данные
+// каждого блока и зашифрованного ключа данных, поэтому их изменение
+// обнаруживается при расшифровке. Блок ключа защищён тем, что ключ данных
+// не расшифруется при изменении соли или параметров KDF, и может быть
+// заменён без перешифрования содержимого файла. Метаданные шифруются ключом
+// данных с теми же дополнительными данными и не меняются при смене ключа.
+//
+// Версии контейнера:
+//
+//	1 — все блоки шифровались одним nonce (не поддерживается)
+//	2 — блоки шифруются по схеме STREAM ключом из пароля (не поддерживается)
+//	3 — блоки шифруются ключом данных из блока ключа
+//	4 — добавлены зашифрованные метаданные
+//
+// Функции получения ключа, которым зашифрован ключ данных:
+//
+//	1 — Argon2id от мастер-пароля (только чтение, файлы прежних версий)
+//	2 — HKDF-SHA256 от ключа хранилища (см. masterkey)
+const (
+	containerVersion    = 4
+	minContainerVersion = 3 // Самая старая читаемая версия
+
+	kdfArgon2id uint8 = 1
+	kdfVaultKey uint8 = 2
+
+	maxChunkSize    = 16 << 20 // Ограничение на размер блока из заголовка
+	maxMetadataSize = 1 << 20  // Ограничение на размер метаданных
+)
+
+var containerMagic = []byte("KEEP")
+
+type header struct {
+	Version     uint8
+	Cipher      uint8
+	ChunkSize   uint32
+	NoncePrefix []byte
+	Key         keyBlock
+}
+
+// keyBlock хранит зашифрованный ключ данных файла.
+type keyBlock struct {
+	KDF        uint8
+	KDFParams  KDFParams
+	Salt       []byte
+	Nonce      []byte
+	WrappedKey []byte
+}
+
+// marshalFixed сериализует неизменяемую часть заголовка до блока ключа.
+func (h *header) marshalFixed() []byte {
+	buf := append([]byte{}, containerMagic...)
+	buf = append(buf, h.Version, h.Cipher)
+	buf = binary.BigEndian.AppendUint32(buf, h.ChunkSize)
+	buf = append(buf, uint8(len(h.NoncePrefix)))
+	buf = append(buf, h.NoncePrefix...)
+	return buf
+}
+
+// marshal сериализует заголовок в байты, которые пишутся в начало файла.
+func (h *header) marshal() []byte {
+	return h.Key.appendTo(h.marshalFixed())
+}
+
+// appendTo дописывает сериализованный блок ключа к buf.
+func (kb *keyBlock) appendTo(buf []byte) []byte {
+	buf = append(buf, kb.KDF)
+	buf = binary.BigEndian.AppendUint32(buf, kb.KDFParams.Time)
+	buf = binary.BigEndian.AppendUint32(buf, kb.KDFParams.Memory)
+	buf = append(buf, kb.KDFParams.Threads, uint8(len(kb.Salt)))
+	buf = append(buf, kb.Salt...)
+	buf = append(buf, uint8(len(kb.Nonce)))
+	buf = append(buf, kb.Nonce...)
+	buf = append(buf, uint8(len(kb.WrappedKey)))
+	buf = append(buf, kb.WrappedKey...)
+	return buf
+}
+
+// readHeader читает заголовок контейнера и возвращает его вместе с исходными
+// байтами неизменяемой части, которые используются как дополнительные данные AEAD.
+func readHeader(r io.Reader) (*header, []byte, error) {
+	var raw bytes.Buffer
+	tr := io.TeeReader(r, &raw)
+
+	magic := make([]byte, len(containerMagic))
+	if _, err := io.ReadFull(tr, magic); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+	}
+	if !bytes.Equal(magic, containerMagic) {
+		return nil, nil, apperrors.ErrUnsupportedFormat
+	}
+
+	var fixed struct {
+		Version   uint8
+		Cipher    uint8
+		ChunkSize uint32
+	}
+	if err := binary.Read(tr, binary.BigEndian, &fixed); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+	}
+	if fixed.Version < minContainerVersion || fixed.Version > containerVersion {
+		return nil, nil, fmt.Errorf("%w: версия контейнера %d", apperrors.ErrUnsupportedFormat, fixed.Version)
+	}
+	if fixed.ChunkSize == 0 || fixed.ChunkSize > maxChunkSize {
+		return nil, nil, fmt.Errorf("%w: размер блока %d", apperrors.ErrUnsupportedFormat, fixed.ChunkSize)
+	}
+
+	h := &header{
+		Version:   fixed.Version,
+		Cipher:    fixed.Cipher,
+		ChunkSize: fixed.ChunkSize,
+	}
+
+	var err error
+	if h.NoncePrefix, err = readBytes(tr); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения nonce: %w", err)
+	}
+	fixedRaw := append([]byte{}, raw.Bytes()...)
+
+	// Блок ключа
+	var kdf struct {
+		KDF     uint8
+		Time    uint32
+		Memory  uint32
+		Threads uint8
+	}
+	if err := binary.Read(tr, binary.BigEndian, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения блока ключа: %w", err)
+	}
+	h.Key = keyBlock{
+		KDF: kdf.KDF,
+		KDFParams: KDFParams{
+			Time:    kdf.Time,
+			Memory:  kdf.Memory,
+			Threads: kdf.Threads,
+		},
+	}
+	if h.Key.KDF == kdfArgon2id {
+		if err := h.Key.KDFParams.validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if h.Key.Salt, err = readBytes(tr); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения соли: %w", err)
+	}
+	if h.Key.Nonce, err = readBytes(tr); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения блока ключа: %w", err)
+	}
+	if h.Key.WrappedKey, err = readBytes(tr); err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения блока ключа: %w", err)
+	}
+
+	return h, fixedRaw, nil
+}
+
+// readBytes читает поле вида len uint8 || [len]byte.
+func readBytes(r io.Reader) ([]byte, error) {
+	var size uint8
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// newDataKey генерирует случайный ключ данных для нового файла.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("ошибка генерации ключа: %w", err)
+	}
+	return key, nil
+}
+
+// wrapKey шифрует ключ данных ключом, полученным из ключа хранилища со
+// свежей солью.
+func wrapKey(dataKey []byte, key *masterkey.Key, suite cipherSuite, additionalData []byte) (keyBlock, error) {
+	kb := keyBlock{
+		KDF:  kdfVaultKey,
+		Salt: make([]byte, saltSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, kb.Salt); err != nil {
+		return keyBlock{}, fmt.Errorf("ошибка генерации соли: %w", err)
+	}
+
+	return kb.wrap(dataKey, key, suite, additionalData)
+}
+
+// wrap шифрует ключ данных ключом, описанным блоком ключа.
+func (kb keyBlock) wrap(dataKey []byte, key *masterkey.Key, suite cipherSuite, additionalData []byte) (keyBlock, error) {
+	kek, err := kb.kek(key)
+	if err != nil {
+		return keyBlock{}, err
+	}
+	aead, err := suite.newAEAD(kek)
+	if err != nil {
+		return keyBlock{}, err
+	}
+
+	kb.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, kb.Nonce); err != nil {
+		return keyBlock{}, fmt.Errorf("ошибка генерации nonce: %w", err)
+	}
+	kb.WrappedKey = aead.Seal(nil, kb.Nonce, dataKey, additionalData)
+
+	return kb, nil
+}
+
+// unwrap расшифровывает ключ данных.
+func (kb *keyBlock) unwrap(key *masterkey.Key, suite cipherSuite, additionalData []byte) ([]byte, error) {
+	kek, err := kb.kek(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := suite.newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(kb.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: размер nonce ключа %d", apperrors.ErrUnsupportedFormat, len(kb.Nonce))
+	}
+
+	dataKey, err := aead.Open(nil, kb.Nonce, kb.WrappedKey, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: не удалось расшифровать ключ файла", apperrors.ErrWrongPassword)
+	}
+	return dataKey, nil
+}
+
+// kek получает ключ шифрования ключа данных по описанию KDF из блока ключа.
+func (kb *keyBlock) kek(key *masterkey.Key) ([]byte, error) {
+	switch kb.KDF {
+	case kdfArgon2id:
+		if err := kb.KDFParams.validate(); err != nil {
+			return nil, err
+		}
+		return deriveKey(key.Password, kb.Salt, kb.KDFParams), nil
+	case kdfVaultKey:
+		if kb.KDFParams != (KDFParams{}) {
+			return nil, fmt.Errorf("%w: параметры KDF %d", apperrors.ErrUnsupportedFormat, kb.KDF)
+		}
+		return deriveFileKey(key.Vault, kb.Salt)
+	default:
+		return nil, fmt.Errorf("%w: KDF %d", apperrors.ErrUnsupportedFormat, kb.KDF)
+	}
+}
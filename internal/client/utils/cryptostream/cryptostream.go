@@ -0,0 +1,337 @@
+// Package cryptostream шифрует и расшифровывает потоки данных в формате
+// контейнера keeper, не держа их целиком в памяти и не создавая временных
+// файлов.
+//
+//	w, err := cryptostream.NewWriter(dst, key)
+//	io.Copy(w, src)
+//	w.Close()
+//
+//	r, err := cryptostream.NewReader(src, key)
+//	io.Copy(dst, r)
+//
+// Вместе с данными в контейнере можно сохранить зашифрованные метаданные
+// произвольного формата (см. WithMetadata и Reader.Metadata).
+package cryptostream
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// DefaultChunkSize размер блока открытого текста в новых контейнерах.
+const DefaultChunkSize = 64 << 10
+
+type options struct {
+	cipher    string
+	chunkSize int
+	workers   int
+	metadata  []byte
+}
+
+// Option настраивает шифрование новых контейнеров. На чтение влияет
+// только WithWorkers.
+type Option func(*options)
+
+// WithCipher задаёт шифр: CipherAES256GCM (по умолчанию) или
+// CipherXChaCha20Poly1305. Контейнер расшифровывается шифром из заголовка.
+func WithCipher(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.cipher = name
+		}
+	}
+}
+
+// WithChunkSize задаёт размер блока открытого текста.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		if size > 0 && size <= maxChunkSize {
+			o.chunkSize = size
+		}
+	}
+}
+
+// WithWorkers задаёт число горутин, которые одновременно шифруют или
+// расшифровывают блоки. По умолчанию — GOMAXPROCS. Порядок блоков
+// сохраняется, а в памяти держится не больше workers блоков.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// WithMetadata задаёт метаданные, которые шифруются вместе с данными.
+func WithMetadata(metadata []byte) Option {
+	return func(o *options) {
+		o.metadata = metadata
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		cipher:    CipherAES256GCM,
+		chunkSize: DefaultChunkSize,
+		workers:   runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ValidateChunkSize проверяет размер блока открытого текста. Ноль означает
+// размер по умолчанию.
+func ValidateChunkSize(size int) error {
+	if size < 0 || size > maxChunkSize {
+		return fmt.Errorf("размер блока %d вне допустимых пределов: от 1 до %d байт", size, maxChunkSize)
+	}
+	return nil
+}
+
+// ValidateCipher проверяет, что шифр с таким названием поддерживается.
+func ValidateCipher(name string) error {
+	_, err := cipherByName(name)
+	return err
+}
+
+// NewWriter пишет в w заголовок нового контейнера и возвращает поток,
+// который шифрует записанные в него данные. Close дописывает последний блок
+// и обязателен: без него контейнер не расшифруется. Нижележащий w
+// не закрывается.
+func NewWriter(w io.Writer, key *masterkey.Key, opts ...Option) (io.WriteCloser, error) {
+	o := newOptions(opts)
+	suite, err := cipherByName(o.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	// Генерируем ключ данных файла
+	dataKey, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Инициализируем шифр
+	aead, err := suite.newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &header{
+		Version:     containerVersion,
+		Cipher:      suite.id,
+		ChunkSize:   uint32(o.chunkSize),
+		NoncePrefix: make([]byte, streamPrefixSize(aead)),
+	}
+
+	// Генерируем уникальный префикс nonce
+	if _, err := io.ReadFull(rand.Reader, h.NoncePrefix); err != nil {
+		return nil, fmt.Errorf("ошибка генерации nonce: %w", err)
+	}
+
+	// Шифруем ключ данных ключом хранилища
+	fixedHeader := h.marshalFixed()
+	h.Key, err = wrapKey(dataKey, key, suite, fixedHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Записываем заголовок в начало потока
+	if _, err := w.Write(h.marshal()); err != nil {
+		return nil, fmt.Errorf("ошибка записи заголовка: %w", err)
+	}
+	if err := writeMetadata(w, aead, h.NoncePrefix, fixedHeader, o.metadata); err != nil {
+		return nil, err
+	}
+
+	return newStreamWriter(w, aead, h.NoncePrefix, fixedHeader, o.chunkSize, o.workers), nil
+}
+
+// Reader поток открытого текста контейнера.
+type Reader struct {
+	r        io.Reader
+	metadata []byte
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+// Metadata возвращает расшифрованные метаданные или nil, если контейнер
+// записан без них.
+func (r *Reader) Metadata() []byte {
+	return r.metadata
+}
+
+// NewReader читает заголовок контейнера из r и возвращает поток открытого
+// текста. Неверный ключ обнаруживается сразу, до чтения данных. Поддерживается
+// и формат без заголовка, записанный прежними версиями клиента.
+func NewReader(r io.Reader, key *masterkey.Key, opts ...Option) (*Reader, error) {
+	o := newOptions(opts)
+	br := bufio.NewReader(r)
+	if !isContainer(br) {
+		lr, err := newLegacyReader(br, key.Password)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{r: lr}, nil
+	}
+
+	f, err := openSealed(br, key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := f.suite.newAEAD(f.dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.header.NoncePrefix) != streamPrefixSize(aead) {
+		return nil, fmt.Errorf("%w: размер nonce %d", apperrors.ErrUnsupportedFormat, len(f.header.NoncePrefix))
+	}
+
+	var metadata []byte
+	if f.header.Version >= 4 {
+		if metadata, err = readMetadata(br, aead, f.header.NoncePrefix, f.fixedHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Reader{
+		r:        newStreamReader(br, aead, f.header.NoncePrefix, f.fixedHeader, int(f.header.ChunkSize), o.workers),
+		metadata: metadata,
+	}, nil
+}
+
+// Rewrap переписывает контейнер из src в dst под новый ключ. У контейнера
+// перешифровывается только ключ данных, метаданные и содержимое копируются
+// как есть. Файл в прежнем формате перешифровывается целиком.
+func Rewrap(src io.Reader, dst io.Writer, oldKey, newKey *masterkey.Key, opts ...Option) error {
+	br := bufio.NewReader(src)
+	if !isContainer(br) {
+		r, err := newLegacyReader(br, oldKey.Password)
+		if err != nil {
+			return err
+		}
+		w, err := NewWriter(dst, newKey, opts...)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
+	f, err := openSealed(br, oldKey)
+	if err != nil {
+		return err
+	}
+
+	h := *f.header
+	h.Key, err = wrapKey(f.dataKey, newKey, f.suite, f.fixedHeader)
+	if err != nil {
+		return err
+	}
+
+	if _, err := dst.Write(h.marshal()); err != nil {
+		return fmt.Errorf("ошибка записи заголовка: %w", err)
+	}
+	if _, err := io.Copy(dst, br); err != nil {
+		return fmt.Errorf("ошибка копирования данных: %w", err)
+	}
+
+	return nil
+}
+
+// CheckKey проверяет ключ, не расшифровывая поток целиком: у контейнера
+// расшифровывается ключ данных, у файла в прежнем формате — первый блок.
+func CheckKey(src io.Reader, key *masterkey.Key) error {
+	_, err := NewReader(src, key)
+	return err
+}
+
+// Verify проверяет подлинность всех блоков файла, не выдавая открытый текст,
+// и сообщает, записан ли файл в прежнем формате без заголовка. Неверный ключ
+// возвращает apperrors.ErrWrongPassword, повреждённые данные —
+// apperrors.ErrDecryptFailed или apperrors.ErrUnsupportedFormat.
+func Verify(src io.Reader, key *masterkey.Key) (legacy bool, err error) {
+	br := bufio.NewReader(src)
+	legacy = !isContainer(br)
+
+	r, err := NewReader(br, key)
+	if err != nil {
+		return legacy, err
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return legacy, err
+	}
+	return legacy, nil
+}
+
+// NeedsPassword сообщает, что файл расшифровывается только мастер-паролем:
+// это файлы прежнего формата без заголовка и контейнеры, ключ данных
+// которых зашифрован ключом Argon2id. Ключом хранилища, восстановленным
+// из долей, такие файлы не открываются.
+func NeedsPassword(src io.Reader) (bool, error) {
+	br := bufio.NewReader(src)
+	if !isContainer(br) {
+		return true, nil
+	}
+	h, _, err := readHeader(br)
+	if err != nil {
+		return false, err
+	}
+	return h.Key.KDF == kdfArgon2id, nil
+}
+
+// sealedFile открытый для чтения контейнер с расшифрованным ключом данных.
+type sealedFile struct {
+	header      *header
+	fixedHeader []byte
+	suite       cipherSuite
+	dataKey     []byte
+}
+
+// isContainer сообщает, начинается ли поток с заголовка контейнера.
+// Файлы без заголовка записаны прежними версиями клиента.
+func isContainer(r *bufio.Reader) bool {
+	magic, err := r.Peek(len(containerMagic))
+	return err == nil && bytes.Equal(magic, containerMagic)
+}
+
+// openSealed читает заголовок контейнера и расшифровывает ключ данных.
+func openSealed(r io.Reader, key *masterkey.Key) (*sealedFile, error) {
+	h, fixedHeader, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Выбираем шифр по идентификатору из заголовка
+	suite, err := cipherByID(h.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	// Расшифровываем ключ данных
+	dataKey, err := h.Key.unwrap(key, suite, fixedHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealedFile{
+		header:      h,
+		fixedHeader: fixedHeader,
+		suite:       suite,
+		dataKey:     dataKey,
+	}, nil
+}
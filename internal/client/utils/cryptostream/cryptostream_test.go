@@ -0,0 +1,588 @@
+package cryptostream
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKDFParams ускоряют тесты, стойкость здесь не важна.
+var testKDFParams = masterkey.Params{Time: 1, Memory: 1024, Threads: 1}
+
+// testKey получает ключи тестового пользователя из пароля.
+func testKey(t *testing.T, password string) *masterkey.Key {
+	key, err := masterkey.Derive("user", password, testKDFParams, nil)
+	require.NoError(t, err)
+	return key
+}
+
+// seal шифрует данные в новый контейнер.
+func seal(t *testing.T, plain []byte, key *masterkey.Key, opts ...Option) []byte {
+	var sealed bytes.Buffer
+	w, err := NewWriter(&sealed, key, opts...)
+	require.NoError(t, err)
+	_, err = w.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return sealed.Bytes()
+}
+
+// open расшифровывает контейнер целиком.
+func open(sealed []byte, key *masterkey.Key) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(sealed), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// sealLegacy шифрует данные в формате без заголовка:
+// nonce || блоки GCM с одним nonce.
+func sealLegacy(t *testing.T, content, password string) []byte {
+	gcm, err := newGCM(legacyDeriveKey(password))
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	legacy := append([]byte{}, nonce...)
+	plain := []byte(content)
+	for len(plain) > 0 {
+		n := min(len(plain), legacyChunkSize)
+		legacy = append(legacy, gcm.Seal(nil, nonce, plain[:n], nil)...)
+		plain = plain[n:]
+	}
+	return legacy
+}
+
+func TestWriterReader(t *testing.T) {
+	key := testKey(t, "password")
+	plain := []byte(strings.Repeat("stream content ", 1000))
+
+	t.Run("roundtrip", func(t *testing.T) {
+		sealed := seal(t, plain, key)
+		opened, err := open(sealed, key)
+		require.NoError(t, err)
+		assert.Equal(t, plain, opened)
+	})
+
+	t.Run("small writes and reads", func(t *testing.T) {
+		var sealed bytes.Buffer
+		w, err := NewWriter(&sealed, key, WithChunkSize(16))
+		require.NoError(t, err)
+		_, err = io.Copy(w, iotest.OneByteReader(bytes.NewReader(plain)))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := NewReader(iotest.HalfReader(&sealed), key)
+		require.NoError(t, err)
+		opened, err := io.ReadAll(iotest.OneByteReader(r))
+		require.NoError(t, err)
+		assert.Equal(t, plain, opened)
+	})
+
+	t.Run("wrong key fails before reading data", func(t *testing.T) {
+		sealed := seal(t, plain, key)
+		_, err := NewReader(bytes.NewReader(sealed), testKey(t, "wrong"))
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+		assert.ErrorIs(t, CheckKey(bytes.NewReader(sealed), testKey(t, "wrong")), apperrors.ErrDecryptFailed)
+		assert.NoError(t, CheckKey(bytes.NewReader(sealed), key))
+	})
+
+	t.Run("unique data key and salt", func(t *testing.T) {
+		first := seal(t, []byte("same content"), key)
+		second := seal(t, []byte("same content"), key)
+
+		firstHeader, _, err := readHeader(bytes.NewReader(first))
+		require.NoError(t, err)
+		secondHeader, _, err := readHeader(bytes.NewReader(second))
+		require.NoError(t, err)
+
+		assert.Equal(t, kdfVaultKey, firstHeader.Key.KDF)
+		assert.Len(t, firstHeader.Key.Salt, saltSize)
+		assert.NotEqual(t, firstHeader.Key.Salt, secondHeader.Key.Salt)
+		assert.NotEqual(t, firstHeader.Key.WrappedKey, secondHeader.Key.WrappedKey)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("write after close", func(t *testing.T) {
+		w, err := NewWriter(io.Discard, key)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		_, err = w.Write([]byte("late"))
+		assert.Error(t, err)
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	key := testKey(t, "password")
+	plain := []byte(strings.Repeat("metadata content ", 100))
+	metadata := []byte(`{"name":"report.pdf"}`)
+
+	sealed := seal(t, plain, key, WithMetadata(metadata))
+	assert.NotContains(t, string(sealed), "report.pdf")
+
+	r, err := NewReader(bytes.NewReader(sealed), key)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, r.Metadata())
+	opened, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	t.Run("kept by rewrap", func(t *testing.T) {
+		newKey := testKey(t, "new")
+		var rewrapped bytes.Buffer
+		require.NoError(t, Rewrap(bytes.NewReader(sealed), &rewrapped, key, newKey))
+
+		r, err := NewReader(&rewrapped, newKey)
+		require.NoError(t, err)
+		assert.Equal(t, metadata, r.Metadata())
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		h, _, err := readHeader(bytes.NewReader(sealed))
+		require.NoError(t, err)
+		tampered := append([]byte{}, sealed...)
+		tampered[len(h.marshal())+4] ^= 1
+
+		_, err = NewReader(bytes.NewReader(tampered), key)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := NewWriter(io.Discard, key, WithMetadata(make([]byte, maxMetadataSize+1)))
+		assert.Error(t, err)
+	})
+
+	t.Run("version 3 without metadata", func(t *testing.T) {
+		suite, err := cipherByName(CipherAES256GCM)
+		require.NoError(t, err)
+		dataKey, err := newDataKey()
+		require.NoError(t, err)
+		aead, err := suite.newAEAD(dataKey)
+		require.NoError(t, err)
+
+		h := &header{
+			Version:     3,
+			Cipher:      suite.id,
+			ChunkSize:   DefaultChunkSize,
+			NoncePrefix: bytes.Repeat([]byte{3}, streamPrefixSize(aead)),
+		}
+		fixedHeader := h.marshalFixed()
+		h.Key, err = wrapKey(dataKey, key, suite, fixedHeader)
+		require.NoError(t, err)
+
+		v3 := bytes.NewBuffer(h.marshal())
+		w := newStreamWriter(v3, aead, h.NoncePrefix, fixedHeader, DefaultChunkSize, 1)
+		_, err = w.Write(plain)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := NewReader(v3, key)
+		require.NoError(t, err)
+		assert.Nil(t, r.Metadata())
+		opened, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, plain, opened)
+	})
+}
+
+func TestTamperedHeader(t *testing.T) {
+	key := testKey(t, "password")
+	data := seal(t, []byte("header content"), key)
+	h, _, err := readHeader(bytes.NewReader(data))
+	require.NoError(t, err)
+	body := data[len(h.marshal()):]
+
+	tamper := func(change func(h header) header) []byte {
+		tampered := change(*h)
+		return append(tampered.marshal(), body...)
+	}
+
+	// Меняем размер блока, не трогая ключ
+	_, err = open(tamper(func(h header) header {
+		h.ChunkSize++
+		return h
+	}), key)
+	assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+
+	// Меняем соль в блоке ключа
+	_, err = open(tamper(func(h header) header {
+		h.Key.Salt = bytes.Repeat([]byte{1}, saltSize)
+		return h
+	}), key)
+	assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+
+	// Ключ хранилища не использует параметры Argon2id
+	_, err = open(tamper(func(h header) header {
+		h.Key.KDFParams.Time++
+		return h
+	}), key)
+	assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat)
+
+	// Неизвестная версия контейнера
+	_, err = open(tamper(func(h header) header {
+		h.Version = containerVersion + 1
+		return h
+	}), key)
+	assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat)
+}
+
+func TestRewrap(t *testing.T) {
+	plain := []byte(strings.Repeat("rewrap me ", 1000))
+	oldKey, newKey := testKey(t, "old"), testKey(t, "new")
+
+	t.Run("container", func(t *testing.T) {
+		before := seal(t, plain, oldKey)
+
+		var after bytes.Buffer
+		err := Rewrap(bytes.NewReader(before), &after, testKey(t, "wrong"), newKey)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+
+		after.Reset()
+		require.NoError(t, Rewrap(bytes.NewReader(before), &after, oldKey, newKey))
+
+		hBefore, _, err := readHeader(bytes.NewReader(before))
+		require.NoError(t, err)
+		hAfter, _, err := readHeader(bytes.NewReader(after.Bytes()))
+		require.NoError(t, err)
+
+		// Меняется только блок ключа, содержимое остаётся прежним
+		assert.Equal(t, hBefore.marshalFixed(), hAfter.marshalFixed())
+		assert.NotEqual(t, hBefore.Key, hAfter.Key)
+		assert.Equal(t, before[len(hBefore.marshal()):], after.Bytes()[len(hAfter.marshal()):])
+
+		_, err = open(after.Bytes(), oldKey)
+		assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+		opened, err := open(after.Bytes(), newKey)
+		require.NoError(t, err)
+		assert.Equal(t, plain, opened)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		legacy := sealLegacy(t, string(plain), "old")
+
+		var after bytes.Buffer
+		require.NoError(t, Rewrap(bytes.NewReader(legacy), &after, oldKey, newKey))
+		assert.True(t, bytes.HasPrefix(after.Bytes(), containerMagic))
+
+		opened, err := open(after.Bytes(), newKey)
+		require.NoError(t, err)
+		assert.Equal(t, plain, opened)
+	})
+}
+
+func TestLegacy(t *testing.T) {
+	content := strings.Repeat("legacy secret ", 1000)
+	legacy := sealLegacy(t, content, "legacypassword")
+
+	opened, err := open(legacy, testKey(t, "legacypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(opened))
+
+	// Неверный пароль обнаруживается по первому блоку
+	_, err = NewReader(bytes.NewReader(legacy), testKey(t, "wrong"))
+	assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+	assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+}
+
+func TestVerify(t *testing.T) {
+	key := testKey(t, "password")
+	plain := []byte(strings.Repeat("verify me ", 1000))
+	sealed := seal(t, plain, key, WithChunkSize(256))
+
+	legacy, err := Verify(bytes.NewReader(sealed), key)
+	assert.NoError(t, err)
+	assert.False(t, legacy)
+
+	_, err = Verify(bytes.NewReader(sealed), testKey(t, "wrong"))
+	assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+
+	// Повреждение в середине файла обнаруживается без неверного ключа
+	corrupted := bytes.Clone(sealed)
+	corrupted[len(corrupted)/2] ^= 1
+	_, err = Verify(bytes.NewReader(corrupted), key)
+	assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+	assert.NotErrorIs(t, err, apperrors.ErrWrongPassword)
+
+	_, err = Verify(bytes.NewReader(sealed[:len(sealed)-1]), key)
+	assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+	assert.NotErrorIs(t, err, apperrors.ErrWrongPassword)
+
+	t.Run("legacy", func(t *testing.T) {
+		old := sealLegacy(t, string(plain), "legacypassword")
+
+		legacy, err := Verify(bytes.NewReader(old), testKey(t, "legacypassword"))
+		assert.NoError(t, err)
+		assert.True(t, legacy)
+
+		legacy, err = Verify(bytes.NewReader(old), testKey(t, "wrong"))
+		assert.ErrorIs(t, err, apperrors.ErrWrongPassword)
+		assert.True(t, legacy)
+	})
+}
+
+func TestPasswordKeyBlock(t *testing.T) {
+	plain := []byte(strings.Repeat("password key block ", 500))
+	key := testKey(t, "password")
+
+	// Контейнер, ключ данных которого зашифрован ключом из мастер-пароля
+	data := seal(t, plain, key)
+	h, fixedHeader, err := readHeader(bytes.NewReader(data))
+	require.NoError(t, err)
+	body := data[len(h.marshal()):]
+
+	suite, err := cipherByID(h.Cipher)
+	require.NoError(t, err)
+	dataKey, err := h.Key.unwrap(key, suite, fixedHeader)
+	require.NoError(t, err)
+	argon := keyBlock{
+		KDF:       kdfArgon2id,
+		KDFParams: KDFParams{Time: 1, Memory: 1024, Threads: 1},
+		Salt:      bytes.Repeat([]byte{2}, saltSize),
+	}
+	h.Key, err = argon.wrap(dataKey, key, suite, fixedHeader)
+	require.NoError(t, err)
+	data = append(h.marshal(), body...)
+
+	// Читается паролем, а не ключом хранилища
+	opened, err := open(data, key)
+	require.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	otherUser, err := masterkey.Derive("other", "password", testKDFParams, nil)
+	require.NoError(t, err)
+	assert.NoError(t, CheckKey(bytes.NewReader(data), otherUser))
+	assert.ErrorIs(t, CheckKey(bytes.NewReader(data), testKey(t, "wrong")), apperrors.ErrDecryptFailed)
+
+	// Смена пароля переводит контейнер на ключ хранилища
+	newKey := testKey(t, "new")
+	var rewrapped bytes.Buffer
+	require.NoError(t, Rewrap(bytes.NewReader(data), &rewrapped, key, newKey))
+	h, _, err = readHeader(bytes.NewReader(rewrapped.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, kdfVaultKey, h.Key.KDF)
+	assert.NoError(t, CheckKey(bytes.NewReader(rewrapped.Bytes()), newKey))
+
+	// Ключом хранилища открываются только контейнеры с ключом хранилища
+	for name, tt := range map[string]struct {
+		data []byte
+		want bool
+	}{
+		"argon2id":  {data, true},
+		"legacy":    {sealLegacy(t, string(plain), "password"), true},
+		"vault key": {rewrapped.Bytes(), false},
+	} {
+		needs, err := NeedsPassword(bytes.NewReader(tt.data))
+		require.NoError(t, err, name)
+		assert.Equal(t, tt.want, needs, name)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := &header{
+		Version:     containerVersion,
+		Cipher:      cipherIDAES256GCM,
+		ChunkSize:   DefaultChunkSize,
+		NoncePrefix: []byte("prefix!"),
+		Key: keyBlock{
+			KDF:        kdfArgon2id,
+			KDFParams:  KDFParams{Time: 2, Memory: 4096, Threads: 3},
+			Salt:       []byte("0123456789abcdef"),
+			Nonce:      []byte("key-nonce..."),
+			WrappedKey: []byte("wrapped data key"),
+		},
+	}
+	raw := h.marshal()
+
+	got, gotFixed, err := readHeader(bytes.NewReader(append(raw, "payload"...)))
+	require.NoError(t, err)
+	assert.Equal(t, h, got)
+	assert.Equal(t, h.marshalFixed(), gotFixed)
+
+	_, _, err = readHeader(bytes.NewReader([]byte("NOPE and more bytes")))
+	assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat)
+
+	_, _, err = readHeader(bytes.NewReader(raw[:len(raw)-1]))
+	assert.Error(t, err)
+
+	// Параметры Argon2id из файла с сервера проверяются до получения ключа
+	for _, params := range []KDFParams{
+		{Time: 0, Memory: 4096, Threads: 1},
+		{Time: 1, Memory: 4096, Threads: 0},
+		{Time: 1, Memory: 4, Threads: 1},
+		{Time: maxKDFTime + 1, Memory: 4096, Threads: 1},
+		{Time: 1, Memory: maxKDFMemory + 1, Threads: 1},
+		{Time: 1, Memory: 4096, Threads: maxKDFThreads + 1},
+	} {
+		bad := *h
+		bad.Key.KDFParams = params
+		_, _, err = readHeader(bytes.NewReader(bad.marshal()))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat, "%+v", params)
+		_, err = bad.Key.kek(testKey(t, "password"))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat, "%+v", params)
+	}
+}
+
+func TestCipherSuites(t *testing.T) {
+	key := testKey(t, "password")
+	plain := []byte(strings.Repeat("cipher suite content ", 500))
+
+	tests := []struct {
+		name string
+		id   uint8
+	}{
+		{name: CipherAES256GCM, id: cipherIDAES256GCM},
+		{name: CipherXChaCha20Poly1305, id: cipherIDXChaCha20Poly1305},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed := seal(t, plain, key, WithCipher(tt.name))
+
+			h, _, err := readHeader(bytes.NewReader(sealed))
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, h.Cipher)
+
+			opened, err := open(sealed, key)
+			require.NoError(t, err)
+			assert.Equal(t, plain, opened)
+		})
+	}
+
+	t.Run("unknown cipher", func(t *testing.T) {
+		assert.Error(t, ValidateCipher("rot13"))
+		_, err := NewWriter(io.Discard, key, WithCipher("rot13"))
+		assert.Error(t, err)
+
+		_, err = cipherByID(0)
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedFormat)
+	})
+}
+
+func TestDeriveKey(t *testing.T) {
+	params := KDFParams{Time: 1, Memory: 1024, Threads: 1}
+	salt := []byte("0123456789abcdef")
+
+	key := deriveKey("password", salt, params)
+	assert.Len(t, key, keySize)
+	assert.Equal(t, key, deriveKey("password", salt, params))
+	assert.NotEqual(t, key, deriveKey("password", []byte("fedcba9876543210"), params))
+	assert.NotEqual(t, key, deriveKey("Password", salt, params))
+	assert.NotEqual(t, key, deriveKey("password", salt, KDFParams{Time: 2, Memory: 1024, Threads: 1}))
+}
+
+func TestDeriveFileKey(t *testing.T) {
+	vault := bytes.Repeat([]byte{7}, keySize)
+	salt := []byte("0123456789abcdef")
+
+	key, err := deriveFileKey(vault, salt)
+	require.NoError(t, err)
+	assert.Len(t, key, keySize)
+	assert.NotEqual(t, vault, key)
+
+	other, err := deriveFileKey(vault, []byte("fedcba9876543210"))
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	_, err = deriveFileKey(nil, salt)
+	assert.Error(t, err)
+}
+
+func TestStream(t *testing.T) {
+	gcm, err := newGCM(bytes.Repeat([]byte{7}, keySize))
+	require.NoError(t, err)
+	prefix := []byte("prefix!")
+	additionalData := []byte("header")
+	size := 16
+
+	// Пачки блоков шифруются параллельно, результат не зависит от числа горутин
+	var reference []byte
+	for _, workers := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			seal := func(t *testing.T, plain []byte) []byte {
+				var sealed bytes.Buffer
+				w := newStreamWriter(&sealed, gcm, prefix, additionalData, size, workers)
+				_, err := w.Write(plain)
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+				return sealed.Bytes()
+			}
+			open := func(sealed []byte) ([]byte, error) {
+				r := newStreamReader(bufio.NewReader(bytes.NewReader(sealed)), gcm, prefix, additionalData, size, workers)
+				return io.ReadAll(r)
+			}
+			block := size + gcm.Overhead()
+
+			for _, length := range []int{0, 1, size - 1, size, size + 1, 3 * size, 3*size + 5} {
+				t.Run(fmt.Sprintf("roundtrip %d bytes", length), func(t *testing.T) {
+					plain := bytes.Repeat([]byte{'x'}, length)
+					sealed := seal(t, plain)
+					chunks := max(1, (length+size-1)/size)
+					assert.Equal(t, chunks*gcm.Overhead()+length, len(sealed))
+
+					opened, err := open(sealed)
+					require.NoError(t, err)
+					assert.Equal(t, plain, append([]byte{}, opened...))
+				})
+			}
+
+			plain := []byte(strings.Repeat("0123456789abcdef", 3))
+			sealed := seal(t, plain)
+
+			t.Run("truncated at chunk boundary", func(t *testing.T) {
+				_, err := open(sealed[:2*block])
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+			})
+
+			t.Run("truncated inside chunk", func(t *testing.T) {
+				_, err := open(sealed[:len(sealed)-1])
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+			})
+
+			t.Run("reordered chunks", func(t *testing.T) {
+				reordered := append([]byte{}, sealed[block:2*block]...)
+				reordered = append(reordered, sealed[:block]...)
+				reordered = append(reordered, sealed[2*block:]...)
+				_, err := open(reordered)
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+			})
+
+			t.Run("data after last chunk", func(t *testing.T) {
+				_, err := open(append(append([]byte{}, sealed...), sealed[:block]...))
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+			})
+
+			t.Run("empty ciphertext", func(t *testing.T) {
+				_, err := open(nil)
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+			})
+
+			t.Run("corrupted chunk keeps preceding data", func(t *testing.T) {
+				corrupted := append([]byte{}, sealed...)
+				corrupted[block+1] ^= 1
+				opened, err := open(corrupted)
+				assert.ErrorIs(t, err, apperrors.ErrDecryptFailed)
+				assert.ErrorContains(t, err, "блок 1")
+				assert.Equal(t, plain[:size], opened)
+			})
+
+			long := seal(t, bytes.Repeat([]byte("0123456789"), 100))
+			if reference == nil {
+				reference = long
+			}
+			assert.Equal(t, reference, long)
+		})
+	}
+}
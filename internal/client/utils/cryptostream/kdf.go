@@ -0,0 +1,63 @@
+package cryptostream
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	keySize  = 32 // AES-256 требует 32-байтный ключ
+	saltSize = 16 // Размер соли, уникальной для каждого файла
+
+	fileKeyContext = "keeper file key"
+
+	// Ограничения параметров Argon2id из заголовка. Файлы приходят с сервера,
+	// поэтому параметры не должны вызывать панику или исчерпание памяти
+	maxKDFTime    = 16
+	maxKDFMemory  = 1 << 20 // 1 ГиБ в КиБ
+	maxKDFThreads = 16
+)
+
+// KDFParams параметры Argon2id из блока ключа файлов, зашифрованных
+// мастер-паролем.
+type KDFParams struct {
+	Time    uint32 // Количество проходов
+	Memory  uint32 // Объём памяти в КиБ
+	Threads uint8  // Степень параллелизма
+}
+
+// validate проверяет, что параметры Argon2id из заголовка допустимы
+// и не превышают ограничений.
+func (p KDFParams) validate() error {
+	if p.Time == 0 || p.Time > maxKDFTime ||
+		p.Threads == 0 || p.Threads > maxKDFThreads ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > maxKDFMemory {
+		return fmt.Errorf("%w: параметры Argon2id t=%d m=%d p=%d", apperrors.ErrUnsupportedFormat, p.Time, p.Memory, p.Threads)
+	}
+	return nil
+}
+
+// deriveKey получает ключ из пароля и соли через Argon2id.
+func deriveKey(password string, salt []byte, p KDFParams) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keySize)
+}
+
+// deriveFileKey получает из ключа хранилища и соли файла ключ, которым
+// шифруется ключ данных.
+func deriveFileKey(vault, salt []byte) ([]byte, error) {
+	if len(vault) != keySize {
+		return nil, errors.New("invalid vault key")
+	}
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, vault, salt, []byte(fileKeyContext)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,100 @@
+package cryptostream
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// Файлы, зашифрованные до появления заголовка контейнера, имеют формат
+// nonce || блоки AES-GCM по 4096 байт открытого текста, а ключом служит
+// пароль, дополненный нулями до 32 байт. Такие файлы только читаются.
+const legacyChunkSize = 4096
+
+// legacyReader расшифровывает файл в формате без заголовка.
+type legacyReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	buf   []byte
+	plain []byte
+	done  bool
+	err   error
+}
+
+// newLegacyReader читает nonce и расшифровывает первый блок, чтобы неверный
+// пароль обнаруживался сразу.
+func newLegacyReader(r io.Reader, password string) (*legacyReader, error) {
+	gcm, err := newGCM(legacyDeriveKey(password))
+	if err != nil {
+		return nil, err
+	}
+
+	// Читаем nonce из начала файла
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("ошибка чтения nonce: %w", err)
+	}
+
+	lr := &legacyReader{
+		r:     r,
+		aead:  gcm,
+		nonce: nonce,
+		buf:   make([]byte, legacyChunkSize+gcm.Overhead()), // Учитываем overhead аутентификации
+	}
+	if err := lr.open(); err != nil {
+		// Проверить пароль отдельно от данных в этом формате нельзя
+		if errors.Is(err, apperrors.ErrDecryptFailed) {
+			return nil, apperrors.ErrWrongPassword
+		}
+		return nil, err
+	}
+	return lr, nil
+}
+
+// legacyDeriveKey повторяет преобразование пароля в ключ из прежних версий.
+func legacyDeriveKey(password string) []byte {
+	key := make([]byte, keySize)
+	copy(key, password)
+	return key
+}
+
+func (lr *legacyReader) Read(p []byte) (int, error) {
+	for len(lr.plain) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		if lr.done {
+			return 0, io.EOF
+		}
+		lr.err = lr.open()
+	}
+
+	n := copy(p, lr.plain)
+	lr.plain = lr.plain[n:]
+	return n, nil
+}
+
+// open читает и расшифровывает следующий блок.
+func (lr *legacyReader) open() error {
+	// Читаем порцию зашифрованных данных
+	n, err := io.ReadFull(lr.r, lr.buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+
+	if n == 0 {
+		lr.done = true
+		return nil
+	}
+
+	// Расшифровываем данные
+	lr.plain, err = lr.aead.Open(lr.buf[:0], lr.nonce, lr.buf[:n], nil)
+	if err != nil {
+		return apperrors.ErrDecryptFailed
+	}
+	return nil
+}
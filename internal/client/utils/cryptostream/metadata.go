@@ -0,0 +1,59 @@
+package cryptostream
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// metadataFlag отличает nonce метаданных от nonce блоков данных, у которых
+// последний байт равен 0 или 1 (см. stream.go).
+const metadataFlag = 2
+
+// metadataNonce собирает nonce метаданных.
+func metadataNonce(prefix []byte) []byte {
+	nonce := make([]byte, 0, len(prefix)+streamSuffixSize)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, math.MaxUint32)
+	return append(nonce, metadataFlag)
+}
+
+// writeMetadata шифрует метаданные и пишет секцию metaLen || meta.
+func writeMetadata(w io.Writer, aead cipher.AEAD, prefix, additionalData, metadata []byte) error {
+	if len(metadata) > maxMetadataSize {
+		return fmt.Errorf("слишком большие метаданные: %d байт", len(metadata))
+	}
+
+	sealed := aead.Seal(nil, metadataNonce(prefix), metadata, additionalData)
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(sealed)))
+	if _, err := w.Write(append(buf, sealed...)); err != nil {
+		return fmt.Errorf("ошибка записи метаданных: %w", err)
+	}
+	return nil
+}
+
+// readMetadata читает и расшифровывает секцию метаданных.
+func readMetadata(r io.Reader, aead cipher.AEAD, prefix, additionalData []byte) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("ошибка чтения метаданных: %w", err)
+	}
+	if size > maxMetadataSize+uint32(aead.Overhead()) {
+		return nil, fmt.Errorf("%w: размер метаданных %d", apperrors.ErrUnsupportedFormat, size)
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(r, sealed); err != nil {
+		return nil, fmt.Errorf("ошибка чтения метаданных: %w", err)
+	}
+
+	metadata, err := aead.Open(nil, metadataNonce(prefix), sealed, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: метаданные", apperrors.ErrDecryptFailed)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,299 @@
+package cryptostream
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// Данные шифруются блоками по схеме STREAM (Hoang, Reyhanitabar, Rogaway,
+// Vizár, «Online Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance»).
+// Nonce каждого блока собирается из случайного префикса, номера блока и флага
+// последнего блока:
+//
+//	prefix [NonceSize-5]byte || counter uint32 (big-endian) || last uint8
+//
+// Поэтому nonce не повторяются в пределах файла, а перестановка, удаление
+// или обрезка блоков приводят к ошибке аутентификации.
+const streamSuffixSize = 5
+
+// streamPrefixSize возвращает размер случайного префикса nonce для шифра.
+func streamPrefixSize(aead cipher.AEAD) int {
+	return aead.NonceSize() - streamSuffixSize
+}
+
+// streamNonce собирает nonce блока с номером counter.
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, len(prefix)+streamSuffixSize)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// readChunk читает ровно len(buf) байт или остаток потока и сообщает,
+// является ли прочитанный блок последним.
+func readChunk(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return n, true, nil
+	case err != nil:
+		return n, false, err
+	}
+
+	// Блок прочитан целиком: он последний, если за ним ничего нет
+	if _, err := r.Peek(1); err != nil {
+		if err == io.EOF {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+// Блоки шифруются независимо, поэтому потоки обрабатывают их пачками
+// по workers блоков, не больше workers горутин одновременно, и пишут
+// результат по порядку. Реализации cipher.AEAD стандартной библиотеки
+// и x/crypto не меняют состояние при Seal и Open и допускают одновременные
+// вызовы.
+
+// streamWriter шифрует данные блоками по size байт. Блок шифруется, когда
+// известно, последний ли он: заполненная пачка блоков ждёт следующей записи
+// или Close.
+type streamWriter struct {
+	w              io.Writer
+	aead           cipher.AEAD
+	prefix         []byte
+	additionalData []byte
+	size           int
+	buf            []byte // Открытый текст пачки, до workers блоков
+	sealed         []byte // Зашифрованные блоки пачки
+	workers        int
+	counter        uint32
+	closed         bool
+	err            error
+}
+
+func newStreamWriter(w io.Writer, aead cipher.AEAD, prefix, additionalData []byte, size, workers int) *streamWriter {
+	workers = max(workers, 1)
+	return &streamWriter{
+		w:              w,
+		aead:           aead,
+		prefix:         prefix,
+		additionalData: additionalData,
+		size:           size,
+		buf:            make([]byte, 0, size*workers),
+		sealed:         make([]byte, (size+aead.Overhead())*workers),
+		workers:        workers,
+	}
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.closed {
+		return 0, errors.New("запись в закрытый поток")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// Заполненная пачка не содержит последнего блока, раз есть ещё данные
+		if len(s.buf) == cap(s.buf) {
+			if err := s.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close шифрует оставшиеся блоки. Пустой поток превращается в один пустой
+// последний блок. Нижележащий io.Writer не закрывается.
+func (s *streamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.seal(true)
+}
+
+// seal шифрует накопленную пачку блоков и пишет её в нижележащий поток.
+// Если last, последний блок пачки — последний блок потока.
+func (s *streamWriter) seal(last bool) error {
+	chunks := max((len(s.buf)+s.size-1)/s.size, 1)
+
+	// Номер последнего блока пачки
+	end := uint64(s.counter) + uint64(chunks) - 1
+	if end > math.MaxUint32 || !last && end == math.MaxUint32 {
+		s.err = errors.New("слишком большой файл для выбранного размера блока")
+		return s.err
+	}
+
+	// Шифруем данные, заголовок аутентифицируется вместе с каждым блоком
+	overhead := s.aead.Overhead()
+	sealed := make([][]byte, chunks)
+	var g errgroup.Group
+	g.SetLimit(s.workers)
+	for i := range chunks {
+		g.Go(func() error {
+			plain := s.buf[i*s.size : min((i+1)*s.size, len(s.buf))]
+			nonce := streamNonce(s.prefix, s.counter+uint32(i), last && i == chunks-1)
+			dst := s.sealed[i*(s.size+overhead) : i*(s.size+overhead)]
+			sealed[i] = s.aead.Seal(dst, nonce, plain, s.additionalData)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		s.err = fmt.Errorf("ошибка шифрования: %w", err)
+		return s.err
+	}
+
+	// Записываем зашифрованные данные по порядку
+	for _, ciphertext := range sealed {
+		if _, err := s.w.Write(ciphertext); err != nil {
+			s.err = fmt.Errorf("ошибка записи зашифрованных данных: %w", err)
+			return s.err
+		}
+	}
+
+	s.buf = s.buf[:0]
+	s.counter += uint32(chunks)
+	return nil
+}
+
+// streamReader расшифровывает поток, записанный streamWriter. Поток без
+// последнего блока или с данными после него отвергается.
+type streamReader struct {
+	r              *bufio.Reader
+	aead           cipher.AEAD
+	prefix         []byte
+	additionalData []byte
+	buf            []byte   // Зашифрованные блоки пачки
+	plain          [][]byte // Ещё не прочитанный открытый текст пачки
+	workers        int
+	counter        uint32
+	done           bool
+	err            error
+}
+
+func newStreamReader(r *bufio.Reader, aead cipher.AEAD, prefix, additionalData []byte, size, workers int) *streamReader {
+	workers = max(workers, 1)
+	return &streamReader{
+		r:              r,
+		aead:           aead,
+		prefix:         prefix,
+		additionalData: additionalData,
+		buf:            make([]byte, (size+aead.Overhead())*workers), // Учитываем overhead аутентификации
+		workers:        workers,
+	}
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) > 0 && len(s.plain[0]) == 0 {
+		s.plain = s.plain[1:]
+	}
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.open()
+		for len(s.plain) > 0 && len(s.plain[0]) == 0 {
+			s.plain = s.plain[1:]
+		}
+	}
+
+	n := copy(p, s.plain[0])
+	s.plain[0] = s.plain[0][n:]
+	return n, nil
+}
+
+// open читает и расшифровывает следующую пачку блоков. Открытый текст
+// блоков до первой ошибки отдаётся читателю до самой ошибки.
+func (s *streamReader) open() error {
+	chunkSize := len(s.buf) / s.workers
+
+	// Читаем блоки зашифрованных данных целиком
+	var chunks [][]byte
+	var lastChunk bool
+	var readErr error
+	for len(chunks) < s.workers && !lastChunk {
+		counter := s.counter + uint32(len(chunks))
+		buf := s.buf[len(chunks)*chunkSize : (len(chunks)+1)*chunkSize]
+
+		n, last, err := readChunk(s.r, buf)
+		if err != nil {
+			readErr = fmt.Errorf("ошибка чтения файла: %w", err)
+			break
+		}
+		if n < s.aead.Overhead() {
+			readErr = fmt.Errorf("%w: блок %d обрезан", apperrors.ErrDecryptFailed, counter)
+			break
+		}
+		chunks = append(chunks, buf[:n])
+		lastChunk = last
+
+		if !last && counter == math.MaxUint32 {
+			readErr = fmt.Errorf("%w: слишком много блоков", apperrors.ErrDecryptFailed)
+			break
+		}
+	}
+
+	// Расшифровываем данные
+	plain := make([][]byte, len(chunks))
+	opened := make([]bool, len(chunks))
+	var g errgroup.Group
+	g.SetLimit(s.workers)
+	for i := range chunks {
+		g.Go(func() error {
+			counter := s.counter + uint32(i)
+			last := lastChunk && i == len(chunks)-1
+
+			var err error
+			plain[i], err = s.aead.Open(chunks[i][:0], streamNonce(s.prefix, counter, last), chunks[i], s.additionalData)
+			if err != nil {
+				return fmt.Errorf("%w: блок %d", apperrors.ErrDecryptFailed, counter)
+			}
+			opened[i] = true
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		// Отдаём читателю блоки до первого нерасшифрованного
+		n := 0
+		for n < len(opened) && opened[n] {
+			n++
+		}
+		s.plain = plain[:n]
+		return err
+	}
+
+	s.plain = plain
+	if readErr != nil {
+		return readErr
+	}
+	s.done = lastChunk
+	s.counter += uint32(len(chunks))
+	return nil
+}
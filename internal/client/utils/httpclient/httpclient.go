@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/aube/keeper/internal/client/utils/atomicfile"
 	"github.com/aube/keeper/internal/client/utils/progress"
 	"github.com/schollz/progressbar/v3"
 )
@@ -119,12 +120,12 @@ func (c *HTTPClient) Post(endpoint string, body interface{}) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// DownloadFile скачивает файл по URL
-func (c *HTTPClient) DownloadFile(endpoint, outputPath string) error {
+// OpenFile открывает поток скачивания файла. Поток нужно закрыть
+func (c *HTTPClient) OpenFile(endpoint string) (io.ReadCloser, error) {
 	// Создаем запрос
 	req, err := http.NewRequest("GET", c.baseURL+endpoint, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Добавляем заголовки
@@ -135,25 +136,38 @@ func (c *HTTPClient) DownloadFile(endpoint, outputPath string) error {
 	// Выполняем запрос
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	// Проверяем статус код
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// DownloadFile скачивает файл по URL
+func (c *HTTPClient) DownloadFile(endpoint, outputPath string) error {
+	body, err := c.OpenFile(endpoint)
+	if err != nil {
+		return err
 	}
+	defer body.Close()
 
-	// Создаем файл
-	out, err := os.Create(outputPath)
+	// Создаем файл: оборванная загрузка не должна выглядеть скачанной
+	out, err := atomicfile.Create(outputPath, 0600)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer out.Abort()
 
 	// Копируем данные в файл
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if _, err := io.Copy(out, body); err != nil {
+		return err
+	}
+	return out.Commit()
 }
 
 // UploadFileWithProgress отправляет файл с отображением прогресса
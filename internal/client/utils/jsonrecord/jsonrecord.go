@@ -0,0 +1,31 @@
+// Package jsonrecord сохраняет записи хранилища в виде JSON: карты,
+// учётные данные и коды TOTP.
+package jsonrecord
+
+import (
+	"encoding/json"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+)
+
+// MIMEType тип содержимого записей в JSON.
+const MIMEType = "application/json"
+
+type FileRepository interface {
+	WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error
+}
+
+// Write кодирует v в JSON, шифрует его в памяти и сохраняет под именем
+// filename с типом содержимого MIMEType. Открытый текст затирается после
+// шифрования, поэтому не остаётся в памяти дольше, чем нужно.
+func Write(repo FileRepository, filename string, v any, key *masterkey.Key, meta entities.Metadata) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	defer clear(data)
+
+	meta.MIMEType = MIMEType
+	return repo.WriteRecord(filename, data, key, meta)
+}
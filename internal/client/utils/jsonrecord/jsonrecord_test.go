@@ -0,0 +1,39 @@
+package jsonrecord
+
+import (
+	"testing"
+
+	"github.com/aube/keeper/internal/client/entities"
+	"github.com/aube/keeper/internal/client/utils/masterkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureRepository запоминает открытый текст на момент шифрования и сам
+// переданный срез.
+type captureRepository struct {
+	name  string
+	plain string
+	data  []byte
+	meta  entities.Metadata
+}
+
+func (r *captureRepository) WriteRecord(name string, data []byte, key *masterkey.Key, meta entities.Metadata) error {
+	r.name, r.plain, r.data, r.meta = name, string(data), data, meta
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	key := &masterkey.Key{Vault: []byte("vault")}
+	repo := &captureRepository{}
+
+	err := Write(repo, "7e6d5c", map[string]string{"secret": "s3cr3t"}, key, entities.Metadata{Alias: "cred_mail.json"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "7e6d5c", repo.name)
+	assert.JSONEq(t, `{"secret":"s3cr3t"}`, repo.plain)
+	assert.Equal(t, "cred_mail.json", repo.meta.Alias)
+	assert.Equal(t, MIMEType, repo.meta.MIMEType)
+	// Открытый текст затёрт после шифрования
+	assert.Equal(t, make([]byte, len(repo.data)), repo.data)
+}
@@ -0,0 +1,66 @@
+package masterkey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+)
+
+// keyFileSize размер случайного ключевого файла в байтах.
+const keyFileSize = 64
+
+// ReadKeyFile читает ключевой файл и возвращает его хеш для Derive.
+// Ключевым может быть любой непустой файл: важно только, чтобы он
+// не менялся.
+func ReadKeyFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", apperrors.ErrKeyFileNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть ключевой файл: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ключевого файла: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("ключевой файл пуст: %s", path)
+	}
+	return h.Sum(nil), nil
+}
+
+// GenerateKeyFile создаёт ключевой файл из случайных байтов. Существующий
+// файл не перезаписывается: без него хранилище не расшифровать.
+func GenerateKeyFile(path string) error {
+	if path == "" {
+		return errors.New("empty key file path")
+	}
+
+	data := make([]byte, keyFileSize)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return fmt.Errorf("ошибка генерации ключевого файла: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("ключевой файл уже существует: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось создать ключевой файл: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("ошибка записи ключевого файла: %w", err)
+	}
+	return f.Close()
+}
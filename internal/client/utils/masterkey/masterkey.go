@@ -0,0 +1,131 @@
+// Package masterkey получает из мастер-пароля два независимых ключа:
+// учётные данные для сервера и ключ хранилища, которым шифруются файлы.
+//
+// Из пароля через Argon2id получается общий секрет, а из него через
+// HKDF-SHA256 с разными метками — ключ авторизации и ключ хранилища.
+// Сервер видит только ключ авторизации, по которому нельзя восстановить
+// ни пароль, ни ключ хранилища.
+//
+// Ключ хранилища может дополнительно зависеть от ключевого файла (см.
+// ReadKeyFile). Тогда для расшифровки файлов мало знать пароль. Ключ
+// авторизации от ключевого файла не зависит, поэтому вход на сервер
+// возможен и без него.
+package masterkey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	keySize = 32
+
+	saltContext  = "keeper master key salt"
+	authContext  = "keeper auth key"
+	vaultContext = "keeper vault key"
+)
+
+// Params параметры Argon2id для мастер-пароля. Ключ авторизации зависит от
+// них, поэтому параметры должны совпадать на всех устройствах пользователя.
+type Params struct {
+	Time    uint32 `json:"time"`    // Количество проходов
+	Memory  uint32 `json:"memory"`  // Объём памяти в КиБ
+	Threads uint8  `json:"threads"` // Степень параллелизма
+}
+
+// DefaultParams возвращает параметры Argon2id, рекомендованные RFC 9106
+// для машин с ограниченным объёмом памяти.
+func DefaultParams() Params {
+	return Params{
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+}
+
+// WithDefaults подставляет значения по умолчанию вместо незаданных параметров.
+func (p Params) WithDefaults() Params {
+	d := DefaultParams()
+	if p.Time == 0 {
+		p.Time = d.Time
+	}
+	if p.Memory == 0 {
+		p.Memory = d.Memory
+	}
+	if p.Threads == 0 {
+		p.Threads = d.Threads
+	}
+	return p
+}
+
+// Key ключи пользователя, полученные из мастер-пароля.
+type Key struct {
+	// Password исходный пароль. Нужен только для чтения файлов, зашифрованных
+	// до появления ключа хранилища.
+	Password string
+	// Auth учётные данные для /register и /login.
+	Auth string
+	// Vault ключ хранилища. Не покидает клиент.
+	Vault []byte
+	// Params параметры Argon2id, с которыми получены ключи.
+	Params Params
+}
+
+// Derive получает ключи из имени пользователя и мастер-пароля. Соль
+// вычисляется из имени пользователя, поэтому ключи одинаковы на всех
+// устройствах и не требуют хранения соли на сервере. Если задан keyFile —
+// хеш ключевого файла из ReadKeyFile, — он участвует в получении ключа
+// хранилища.
+func Derive(username, password string, p Params, keyFile []byte) (*Key, error) {
+	if username == "" {
+		return nil, errors.New("empty username")
+	}
+	if password == "" {
+		return nil, errors.New("empty password")
+	}
+
+	p = p.WithDefaults()
+	master := argon2.IDKey([]byte(password), salt(username), p.Time, p.Memory, p.Threads, keySize)
+
+	auth, err := expand(master, authContext)
+	if err != nil {
+		return nil, err
+	}
+	vault, err := expandSalted(master, keyFile, vaultContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		Password: password,
+		Auth:     hex.EncodeToString(auth),
+		Vault:    vault,
+		Params:   p,
+	}, nil
+}
+
+// salt вычисляет соль Argon2id из имени пользователя.
+func salt(username string) []byte {
+	sum := sha256.Sum256([]byte(saltContext + "\x00" + username))
+	return sum[:16]
+}
+
+// expand получает из секрета ключ с меткой info.
+func expand(secret []byte, info string) ([]byte, error) {
+	return expandSalted(secret, nil, info)
+}
+
+// expandSalted получает из секрета ключ с меткой info и солью HKDF salt.
+// Пустая соль даёт тот же ключ, что и expand.
+func expandSalted(secret, salt []byte, info string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
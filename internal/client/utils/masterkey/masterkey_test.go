@@ -0,0 +1,117 @@
+package masterkey
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aube/keeper/internal/client/utils/apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams ускоряют тесты, стойкость здесь не важна.
+var testParams = Params{Time: 1, Memory: 1024, Threads: 1}
+
+func TestDerive(t *testing.T) {
+	key, err := Derive("user", "password", testParams, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "password", key.Password)
+	assert.Len(t, key.Auth, 2*keySize)
+	assert.Len(t, key.Vault, keySize)
+	assert.Equal(t, testParams, key.Params)
+
+	// Ключ авторизации не совпадает с ключом хранилища и не раскрывает пароль
+	assert.NotEqual(t, key.Auth, "password")
+	assert.NotContains(t, key.Auth, "70617373776f7264")
+	assert.False(t, bytes.Contains([]byte(key.Auth), key.Vault))
+
+	t.Run("deterministic", func(t *testing.T) {
+		again, err := Derive("user", "password", testParams, nil)
+		require.NoError(t, err)
+		assert.Equal(t, key, again)
+	})
+
+	t.Run("depends on inputs", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			username string
+			password string
+			params   Params
+		}{
+			{"username", "other", "password", testParams},
+			{"password", "user", "Password", testParams},
+			{"params", "user", "password", Params{Time: 2, Memory: 1024, Threads: 1}},
+		} {
+			other, err := Derive(tt.username, tt.password, tt.params, nil)
+			require.NoError(t, err, tt.name)
+			assert.NotEqual(t, key.Auth, other.Auth, tt.name)
+			assert.NotEqual(t, key.Vault, other.Vault, tt.name)
+		}
+	})
+
+	t.Run("key file", func(t *testing.T) {
+		withFile, err := Derive("user", "password", testParams, []byte("key file digest"))
+		require.NoError(t, err)
+		assert.Equal(t, key.Auth, withFile.Auth)
+		assert.NotEqual(t, key.Vault, withFile.Vault)
+
+		other, err := Derive("user", "password", testParams, []byte("other digest"))
+		require.NoError(t, err)
+		assert.NotEqual(t, withFile.Vault, other.Vault)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := Derive("", "password", testParams, nil)
+		assert.EqualError(t, err, "empty username")
+
+		_, err = Derive("user", "", testParams, nil)
+		assert.EqualError(t, err, "empty password")
+	})
+}
+
+func TestParamsWithDefaults(t *testing.T) {
+	assert.Equal(t, DefaultParams(), Params{}.WithDefaults())
+	assert.Equal(t, Params{Time: 1, Memory: 64 * 1024, Threads: 4}, Params{Time: 1}.WithDefaults())
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.key")
+
+	require.NoError(t, GenerateKeyFile(path))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(keyFileSize), fi.Size())
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	digest, err := ReadKeyFile(path)
+	require.NoError(t, err)
+	assert.Len(t, digest, 32)
+
+	again, err := ReadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, digest, again)
+
+	t.Run("no overwrite", func(t *testing.T) {
+		err := GenerateKeyFile(path)
+		assert.ErrorContains(t, err, "ключевой файл уже существует")
+		same, err := ReadKeyFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, digest, same)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := ReadKeyFile(filepath.Join(dir, "missing.key"))
+		assert.ErrorIs(t, err, apperrors.ErrKeyFileNotFound)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty.key")
+		require.NoError(t, os.WriteFile(empty, nil, 0600))
+		_, err := ReadKeyFile(empty)
+		assert.ErrorContains(t, err, "ключевой файл пуст")
+	})
+}
@@ -0,0 +1,152 @@
+// Package shamir разделяет секрет на доли по схеме Шамира над полем GF(2^8).
+//
+// Каждый байт секрета — свободный член случайного многочлена степени
+// threshold-1. Доля содержит значения всех многочленов в одной ненулевой
+// точке x. Любые threshold долей восстанавливают секрет интерполяцией
+// Лагранжа, а меньшее число долей не раскрывает о нём ничего.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxShares наибольшее число долей: точки x — ненулевые элементы GF(2^8).
+const MaxShares = 255
+
+// Share доля секрета.
+type Share struct {
+	X byte   // Точка, в которой вычислены многочлены
+	Y []byte // Значения многочленов, по байту на байт секрета
+}
+
+// Split разделяет секрет на n долей, из которых для восстановления
+// достаточно любых threshold.
+func Split(secret []byte, n, threshold int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("empty secret")
+	}
+	if threshold < 2 {
+		return nil, errors.New("threshold must be at least 2")
+	}
+	if n < threshold {
+		return nil, fmt.Errorf("shares (%d) must not be less than threshold (%d)", n, threshold)
+	}
+	if n > MaxShares {
+		return nil, fmt.Errorf("too many shares: %d, maximum %d", n, MaxShares)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+
+	coeffs := make([]byte, threshold)
+	defer clear(coeffs)
+	for b, s := range secret {
+		coeffs[0] = s
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("ошибка генерации долей: %w", err)
+		}
+		for i := range shares {
+			shares[i].Y[b] = eval(coeffs, shares[i].X)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine восстанавливает секрет из долей. Долей должно быть не меньше
+// порога, с которым секрет разделён: иначе результат будет неверным,
+// и проверить его может только вызывающий.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+
+	size := len(shares[0].Y)
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.X == 0 {
+			return nil, errors.New("invalid share point")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("duplicate share %d", s.X)
+		}
+		seen[s.X] = true
+		if len(s.Y) != size || size == 0 {
+			return nil, errors.New("shares have different lengths")
+		}
+	}
+
+	// Коэффициенты Лагранжа для x = 0 одинаковы для всех байтов секрета
+	basis := make([]byte, len(shares))
+	for i, si := range shares {
+		basis[i] = 1
+		for j, sj := range shares {
+			if i != j {
+				// В GF(2^8) вычитание совпадает со сложением: 0 - xj = xj
+				basis[i] = mul(basis[i], div(sj.X, sj.X^si.X))
+			}
+		}
+	}
+
+	secret := make([]byte, size)
+	for b := range secret {
+		for i, s := range shares {
+			secret[b] ^= mul(basis[i], s.Y[b])
+		}
+	}
+
+	return secret, nil
+}
+
+// eval вычисляет многочлен с коэффициентами coeffs в точке x по схеме Горнера.
+func eval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// Таблицы логарифмов и степеней образующего 3 в GF(2^8) с многочленом
+// x^8 + x^4 + x^3 + x + 1, как в AES.
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		// Умножение на 3: x*2 + x
+		x2 := x << 1
+		if x&0x80 != 0 {
+			x2 ^= 0x1b
+		}
+		x = x2 ^ x
+	}
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func div(a, b byte) byte {
+	if b == 0 {
+		panic("shamir: division by zero")
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
@@ -0,0 +1,80 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			assert.Equal(t, byte(a), div(mul(byte(a), byte(b)), byte(b)))
+		}
+	}
+	// Пример из FIPS-197: {57} * {83} = {c1}
+	assert.Equal(t, byte(0xc1), mul(0x57, 0x83))
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	shares, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+
+	t.Run("any threshold subset", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			for j := i + 1; j < 5; j++ {
+				for k := j + 1; k < 5; k++ {
+					got, err := Combine([]Share{shares[k], shares[i], shares[j]})
+					require.NoError(t, err)
+					assert.Equal(t, secret, got)
+				}
+			}
+		}
+
+		got, err := Combine(shares)
+		require.NoError(t, err)
+		assert.Equal(t, secret, got)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		got, err := Combine(shares[:2])
+		require.NoError(t, err)
+		assert.NotEqual(t, secret, got)
+	})
+
+	t.Run("random shares", func(t *testing.T) {
+		again, err := Split(secret, 5, 3)
+		require.NoError(t, err)
+		assert.False(t, bytes.Equal(shares[0].Y, again[0].Y))
+	})
+
+	t.Run("invalid shares", func(t *testing.T) {
+		_, err := Combine(shares[:1])
+		assert.Error(t, err)
+
+		_, err = Combine([]Share{shares[0], shares[0]})
+		assert.EqualError(t, err, "duplicate share 1")
+
+		_, err = Combine([]Share{shares[0], {X: 2, Y: []byte{1}}})
+		assert.EqualError(t, err, "shares have different lengths")
+	})
+}
+
+func TestSplitValidation(t *testing.T) {
+	_, err := Split(nil, 3, 2)
+	assert.EqualError(t, err, "empty secret")
+
+	_, err = Split([]byte("s"), 3, 1)
+	assert.EqualError(t, err, "threshold must be at least 2")
+
+	_, err = Split([]byte("s"), 2, 3)
+	assert.EqualError(t, err, "shares (2) must not be less than threshold (3)")
+
+	_, err = Split([]byte("s"), 256, 3)
+	assert.EqualError(t, err, "too many shares: 256, maximum 255")
+}
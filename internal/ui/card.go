@@ -2,52 +2,64 @@
 package ui
 
 import (
-	"fmt"
+	"errors"
 
+	"github.com/aube/keeper/internal/client/utils/apperrors"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Поля экрана карты, для которых проверка возвращает ошибки
+var cardFields = map[string]int{
+	"number": 0,
+	"date":   1,
+	"cvv":    2,
+	"pin":    5,
+}
+
+// CardScreen экран добавления карты. Поля проверяются до шифрования,
+// ошибки выводятся под полями. Карту с истёкшим сроком действия нужно
+// подтвердить повторным нажатием Enter.
 type CardScreen struct {
-	width    int
-	height   int
-	api      CardScreenAPI
-	inputs   []textinput.Model
-	focus    int
-	errorMsg string
+	width       int
+	height      int
+	api         CardScreenAPI
+	inputs      []textinput.Model
+	focus       int
+	errorMsg    string
+	fieldErrors map[int]string
+	warning     string
 }
 
 func NewCardScreen(api CardScreenAPI) CardScreen {
 	a := CardScreen{
 		api:    api,
-		inputs: make([]textinput.Model, 4),
+		inputs: make([]textinput.Model, 8),
 	}
 
-	a.inputs[0] = textinput.New()
-	a.inputs[0].Placeholder = "Номер"
+	placeholders := []string{"Номер", "Срок действия (MM/YY)", "CVV", "Владелец", "Банк", "PIN", "Заметки", "Пароль"}
+	for i := range a.inputs {
+		a.inputs[i] = textinput.New()
+		a.inputs[i].Placeholder = placeholders[i]
+		a.inputs[i].CharLimit = 256
+		a.inputs[i].Prompt = "┃ "
+	}
 	a.inputs[0].CharLimit = 32
 	a.inputs[0].Focus()
-	a.inputs[0].Prompt = "┃ "
 	a.inputs[0].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	// a.inputs[0].SetValue(api.GetLastRegister())
-
-	a.inputs[1] = textinput.New()
-	a.inputs[1].Placeholder = "Дата"
 	a.inputs[1].CharLimit = 5
-	a.inputs[1].Prompt = "┃ "
-
-	a.inputs[2] = textinput.New()
-	a.inputs[2].Placeholder = "CVV"
-	a.inputs[2].CharLimit = 5
-	a.inputs[2].Prompt = "┃ "
-
-	a.inputs[3] = textinput.New()
-	a.inputs[3].Placeholder = "Пароль"
-	a.inputs[3].CharLimit = 32
-	a.inputs[3].Prompt = "┃ "
-	a.inputs[3].EchoMode = textinput.EchoPassword
-	a.inputs[3].EchoCharacter = '•'
+	a.inputs[2].CharLimit = 4
+	a.inputs[3].CharLimit = 64
+	a.inputs[4].CharLimit = 64
+	a.inputs[5].CharLimit = 12
+	a.inputs[7].CharLimit = 32
+
+	// PIN и мастер-пароль скрываются
+	for _, i := range []int{5, 7} {
+		a.inputs[i].EchoMode = textinput.EchoPassword
+		a.inputs[i].EchoCharacter = '•'
+	}
 
 	return a
 }
@@ -66,19 +78,7 @@ func (a CardScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			s := msg.String()
 
 			if s == "enter" && a.focus == len(a.inputs)-1 {
-				number := a.inputs[0].Value()
-				date := a.inputs[1].Value()
-				cvv := a.inputs[2].Value()
-				password := a.inputs[3].Value()
-
-				if err := a.api.Card(number, date, cvv, password); err != nil {
-					a.errorMsg = err.Error()
-					return a, nil
-				}
-
-				return a, func() tea.Msg {
-					return SwitchScreenMsg{ScreenName: "menu"}
-				}
+				return a.submit()
 			}
 
 			// Циклическая навигация между полями
@@ -93,7 +93,6 @@ func (a CardScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if a.focus < 0 {
 				a.focus = len(a.inputs) - 1
 			}
-			fmt.Println("a.focus", a.focus)
 			// Устанавливаем фокус на текущее поле
 			cmds = make([]tea.Cmd, len(a.inputs))
 			for i := range a.inputs {
@@ -111,6 +110,11 @@ func (a CardScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Изменённые данные нужно проверить заново
+	if _, ok := msg.(tea.KeyMsg); ok {
+		a.warning = ""
+	}
+
 	// Обновляем текущее поле ввода
 	var cmd tea.Cmd
 	a.inputs[a.focus], cmd = a.inputs[a.focus].Update(msg)
@@ -119,8 +123,55 @@ func (a CardScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, tea.Batch(cmds...)
 }
 
+// submit проверяет поля карты и сохраняет её. Ошибки проверки выводятся
+// под полями, шифрование при этом не начинается.
+func (a CardScreen) submit() (tea.Model, tea.Cmd) {
+	number := a.inputs[0].Value()
+	date := a.inputs[1].Value()
+	cvv := a.inputs[2].Value()
+	holder := a.inputs[3].Value()
+	bank := a.inputs[4].Value()
+	pin := a.inputs[5].Value()
+	notes := a.inputs[6].Value()
+	password := a.inputs[7].Value()
+
+	a.errorMsg = ""
+	a.fieldErrors = nil
+
+	// Предупреждение уже показано, повторный Enter подтверждает сохранение
+	if a.warning == "" {
+		_, expired, err := a.api.CheckCard(number, date, cvv, pin)
+		var fields apperrors.FieldErrors
+		if errors.As(err, &fields) {
+			a.fieldErrors = make(map[int]string, len(fields))
+			for field, msg := range fields {
+				a.fieldErrors[cardFields[field]] = msg
+			}
+			return a, nil
+		}
+		if err != nil {
+			a.errorMsg = err.Error()
+			return a, nil
+		}
+		if expired {
+			a.warning = "Срок действия карты истёк. Enter — сохранить всё равно"
+			return a, nil
+		}
+	}
+
+	if err := a.api.Card(number, date, cvv, holder, bank, pin, notes, password); err != nil {
+		a.warning = ""
+		a.errorMsg = err.Error()
+		return a, nil
+	}
+
+	return a, func() tea.Msg {
+		return SwitchScreenMsg{ScreenName: "menu"}
+	}
+}
+
 func (a CardScreen) View() string {
-	title := "Регистрация"
+	title := "Добавление карты"
 	styledTitle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("63")).
 		Align(lipgloss.Center).
@@ -131,34 +182,28 @@ func (a CardScreen) View() string {
 	inputStyle := lipgloss.NewStyle().
 		Width(30).
 		Padding(0, 1)
-
-	// Собираем поля ввода
-	inputs := []string{
-		inputStyle.Render(a.inputs[0].View()),
-		inputStyle.Render(a.inputs[1].View()),
-		inputStyle.Render(a.inputs[2].View()),
-		inputStyle.Render(a.inputs[3].View()),
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196"))
+
+	// Собираем поля ввода с подписями и ошибками проверки
+	var fields []string
+	for i := range a.inputs {
+		fields = append(fields, a.inputs[i].Placeholder+":", inputStyle.Render(a.inputs[i].View()))
+		if msg, ok := a.fieldErrors[i]; ok {
+			fields = append(fields, errorStyle.Render("  "+msg))
+		}
+	}
+	form := lipgloss.JoinVertical(lipgloss.Left, fields...)
+
+	// Добавляем предупреждение и сообщение об ошибке
+	if a.warning != "" {
+		warningStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Render(a.warning)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", warningStyle)
 	}
-
-	// Добавляем подписи
-	form := lipgloss.JoinVertical(
-		lipgloss.Left,
-		a.inputs[0].Placeholder+":",
-		inputs[0],
-		a.inputs[1].Placeholder+":",
-		inputs[1],
-		a.inputs[2].Placeholder+":",
-		inputs[2],
-		a.inputs[3].Placeholder+":",
-		inputs[3],
-	)
-
-	// Добавляем сообщение об ошибке
 	if a.errorMsg != "" {
-		errorStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196")).
-			Render("Ошибка: " + a.errorMsg)
-		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle.Render("Ошибка: "+a.errorMsg))
 	}
 
 	// Кнопка отправки
@@ -168,7 +213,7 @@ func (a CardScreen) View() string {
 	}
 	submit := lipgloss.NewStyle().
 		MarginTop(1).
-		Render(fmt.Sprintf("%s Войти (Enter)", submitBtn))
+		Render(submitBtn + " Сохранить (Enter)")
 
 	// Возврат в меню
 	back := lipgloss.NewStyle().
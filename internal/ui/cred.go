@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CredScreen экран добавления и изменения учётных данных. При изменении
+// пустые поля сохраняют прежние значения, а поля, отмеченные ctrl+x,
+// очищаются.
+type CredScreen struct {
+	width    int
+	height   int
+	api      CredScreenAPI
+	update   bool
+	inputs   []textinput.Model
+	cleared  []bool // Поля, которые нужно очистить при изменении
+	focus    int
+	errorMsg string
+}
+
+// Поля записи, которые можно очистить на экране изменения: адрес, логин,
+// пароль записи и заметки.
+const (
+	firstClearable = 1
+	lastClearable  = 4
+)
+
+func NewCredScreen(api CredScreenAPI, update bool) CredScreen {
+	a := CredScreen{
+		api:     api,
+		update:  update,
+		inputs:  make([]textinput.Model, 6),
+		cleared: make([]bool, 6),
+	}
+
+	placeholders := []string{"Имя записи", "Адрес", "Логин", "Пароль записи", "Заметки", "Пароль"}
+	for i := range a.inputs {
+		a.inputs[i] = textinput.New()
+		a.inputs[i].Placeholder = placeholders[i]
+		a.inputs[i].CharLimit = 256
+		a.inputs[i].Prompt = "┃ "
+	}
+	a.inputs[0].CharLimit = 64
+	a.inputs[0].Focus()
+	a.inputs[0].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	// Пароль записи и мастер-пароль скрываются
+	for _, i := range []int{3, 5} {
+		a.inputs[i].EchoMode = textinput.EchoPassword
+		a.inputs[i].EchoCharacter = '•'
+	}
+	a.inputs[5].CharLimit = 32
+
+	return a
+}
+
+func (a CredScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (a CredScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			if s == "enter" && a.focus == len(a.inputs)-1 {
+				name := a.inputs[0].Value()
+				url := a.inputs[1].Value()
+				login := a.inputs[2].Value()
+				secret := a.inputs[3].Value()
+				notes := a.inputs[4].Value()
+				password := a.inputs[5].Value()
+
+				var err error
+				if a.update {
+					err = a.api.CredUpdate(name, a.changed(1, url), a.changed(2, login), a.changed(3, secret), a.changed(4, notes), password)
+				} else {
+					err = a.api.CredAdd(name, url, login, secret, notes, password)
+				}
+				if err != nil {
+					a.errorMsg = err.Error()
+					return a, nil
+				}
+
+				return a, func() tea.Msg {
+					return SwitchScreenMsg{ScreenName: "menu"}
+				}
+			}
+
+			// Циклическая навигация между полями
+			if s == "up" || s == "shift+tab" {
+				a.focus--
+			} else {
+				a.focus++
+			}
+
+			if a.focus >= len(a.inputs) {
+				a.focus = 0
+			} else if a.focus < 0 {
+				a.focus = len(a.inputs) - 1
+			}
+			// Устанавливаем фокус на текущее поле
+			cmds = make([]tea.Cmd, len(a.inputs))
+			for i := range a.inputs {
+				if i == a.focus {
+					cmds[i] = a.inputs[i].Focus()
+				} else {
+					a.inputs[i].Blur()
+				}
+			}
+			return a, tea.Batch(cmds...)
+
+		case "ctrl+x":
+			// Отметка очистки поля, введённое значение сбрасывается
+			if a.update && a.focus >= firstClearable && a.focus <= lastClearable {
+				a.cleared[a.focus] = !a.cleared[a.focus]
+				a.inputs[a.focus].SetValue("")
+			}
+			return a, nil
+
+		case "esc":
+
+			return a, tea.Quit
+		}
+	}
+
+	// Обновляем текущее поле ввода
+	var cmd tea.Cmd
+	a.inputs[a.focus], cmd = a.inputs[a.focus].Update(msg)
+	cmds = append(cmds, cmd)
+
+	// Введённое значение отменяет очистку поля
+	if a.inputs[a.focus].Value() != "" {
+		a.cleared[a.focus] = false
+	}
+
+	return a, tea.Batch(cmds...)
+}
+
+func (a CredScreen) View() string {
+	title := "Добавление учётных данных"
+	action := "Сохранить"
+	if a.update {
+		title = "Изменение учётных данных"
+		action = "Изменить"
+	}
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для полей ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	// Собираем поля ввода с подписями
+	var fields []string
+	for i := range a.inputs {
+		label := a.inputs[i].Placeholder + ":"
+		if a.cleared[i] {
+			label += " будет очищено"
+		}
+		fields = append(fields, label, inputStyle.Render(a.inputs[i].View()))
+	}
+	form := lipgloss.JoinVertical(lipgloss.Left, fields...)
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submitBtn := " "
+	if a.focus == len(a.inputs)-1 {
+		submitBtn = ">"
+	}
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(submitBtn + " " + action + " (Enter)")
+
+	// Возврат в меню
+	help := "ESC: Отмена"
+	if a.update {
+		help = "ctrl+x: очистить поле • " + help
+	}
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(help)
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+// changed возвращает изменение поля i: отмеченное поле очищается, пустое
+// сохраняет прежнее значение.
+func (a CredScreen) changed(i int, value string) *string {
+	if a.cleared[i] {
+		value = ""
+		return &value
+	}
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (a *CredScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}
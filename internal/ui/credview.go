@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CredViewScreen экран просмотра учётных данных. Без имени записи
+// показывает список всех записей.
+type CredViewScreen struct {
+	width    int
+	height   int
+	api      CredViewScreenAPI
+	inputs   []textinput.Model
+	focus    int
+	report   string
+	errorMsg string
+}
+
+func NewCredViewScreen(api CredViewScreenAPI) CredViewScreen {
+	a := CredViewScreen{
+		api:    api,
+		inputs: make([]textinput.Model, 2),
+	}
+
+	a.inputs[0] = textinput.New()
+	a.inputs[0].Placeholder = "Имя записи"
+	a.inputs[0].CharLimit = 64
+	a.inputs[0].Focus()
+	a.inputs[0].Prompt = "┃ "
+	a.inputs[0].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	a.inputs[1] = textinput.New()
+	a.inputs[1].Placeholder = "Пароль"
+	a.inputs[1].CharLimit = 32
+	a.inputs[1].Prompt = "┃ "
+	a.inputs[1].EchoMode = textinput.EchoPassword
+	a.inputs[1].EchoCharacter = '•'
+
+	return a
+}
+
+func (a CredViewScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (a CredViewScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			if s == "enter" && a.focus == len(a.inputs)-1 {
+				name := a.inputs[0].Value()
+				password := a.inputs[1].Value()
+
+				a.report, a.errorMsg = "", ""
+				var err error
+				if name == "" {
+					a.report, err = a.api.CredList(password)
+				} else {
+					a.report, err = a.api.CredGet(name, password)
+				}
+				if err != nil {
+					a.errorMsg = err.Error()
+				}
+				return a, nil
+			}
+
+			// Переключение между полями
+			if s == "up" || s == "shift+tab" {
+				a.focus--
+			} else {
+				a.focus++
+			}
+
+			if a.focus >= len(a.inputs) {
+				a.focus = 0
+			} else if a.focus < 0 {
+				a.focus = len(a.inputs) - 1
+			}
+			// Устанавливаем фокус на текущее поле
+			cmds = make([]tea.Cmd, len(a.inputs))
+			for i := range a.inputs {
+				if i == a.focus {
+					cmds[i] = a.inputs[i].Focus()
+				} else {
+					a.inputs[i].Blur()
+				}
+			}
+			return a, tea.Batch(cmds...)
+
+		case "esc":
+
+			return a, tea.Quit
+		}
+	}
+
+	// Обновляем текущее поле ввода
+	var cmd tea.Cmd
+	a.inputs[a.focus], cmd = a.inputs[a.focus].Update(msg)
+	cmds = append(cmds, cmd)
+
+	return a, tea.Batch(cmds...)
+}
+
+func (a CredViewScreen) View() string {
+	title := "Учётные данные"
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для полей ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	form := lipgloss.JoinVertical(
+		lipgloss.Left,
+		a.inputs[0].Placeholder+" (пусто — список записей):",
+		inputStyle.Render(a.inputs[0].View()),
+		a.inputs[1].Placeholder+":",
+		inputStyle.Render(a.inputs[1].View()),
+	)
+
+	// Добавляем найденную запись или список
+	if a.report != "" {
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", a.report)
+	}
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submitBtn := " "
+	if a.focus == len(a.inputs)-1 {
+		submitBtn = ">"
+	}
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(submitBtn + " Показать (Enter)")
+
+	// Возврат в меню
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("ESC: Отмена")
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+func (a *CredViewScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}
@@ -2,8 +2,10 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/aube/keeper/internal/client/utils/apperrors"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -61,11 +63,17 @@ func (a DecryptScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			s := msg.String()
 
 			if s == "enter" && a.focus == len(a.inputs)-1 {
-				login := a.inputs[0].Value()
-				email := a.inputs[1].Value()
+				output := a.inputs[0].Value()
+				input := a.inputs[1].Value()
 				password := a.inputs[2].Value()
 
-				if err := a.api.Decrypt(login, password, email); err != nil {
+				// Ошибки пароля и ключевого файла показываются на экране
+				if err := a.api.Decrypt(password, input, output); err != nil {
+					// Неверный пароль обнаруживается до создания файла:
+					// достаточно ввести пароль заново
+					if errors.Is(err, apperrors.ErrWrongPassword) {
+						a.inputs[2].Reset()
+					}
 					a.errorMsg = err.Error()
 					return a, nil
 				}
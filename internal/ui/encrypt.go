@@ -64,11 +64,12 @@ func (a EncryptScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			s := msg.String()
 
 			if s == "enter" && a.focus == len(a.inputs)-1 {
-				login := a.inputs[0].Value()
-				email := a.inputs[1].Value()
+				input := a.inputs[0].Value()
+				output := a.inputs[1].Value()
 				password := a.inputs[2].Value()
 
-				if err := a.api.Encrypt(login, password, email); err != nil {
+				// Ошибки пароля и ключевого файла показываются на экране
+				if err := a.api.Encrypt(password, input, output); err != nil {
 					a.errorMsg = err.Error()
 					return a, nil
 				}
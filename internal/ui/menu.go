@@ -25,7 +25,15 @@ func NewMenuScreen() MenuScreen {
 			"Удалить файл",
 			"Добавить карту",
 			"Удалить карту",
+			"Добавить учётные данные",
+			"Изменить учётные данные",
+			"Учётные данные",
+			"Новая заметка",
+			"Заметки",
+			"Одноразовый код",
 			"Синхронизация",
+			"Проверить файлы",
+			"Сменить пароль",
 			"Выход",
 		},
 		screens: []string{
@@ -36,7 +44,15 @@ func NewMenuScreen() MenuScreen {
 			"delete",
 			"card",
 			"deletecard",
+			"cred",
+			"credupdate",
+			"credview",
+			"note",
+			"noteview",
+			"totp",
 			"sync",
+			"verify",
+			"rekey",
 			"",
 		},
 	}
@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Поля экрана заметки в порядке перехода по Tab
+const (
+	noteFocusName = iota
+	noteFocusText
+	noteFocusPassword
+)
+
+// NoteScreen экран создания и просмотра заметки. При просмотре поле текста
+// только показывает расшифрованную заметку и пропускается при навигации.
+type NoteScreen struct {
+	width    int
+	height   int
+	api      NoteScreenAPI
+	view     bool
+	name     textinput.Model
+	password textinput.Model
+	text     textarea.Model
+	focus    int
+	errorMsg string
+}
+
+func NewNoteScreen(api NoteScreenAPI, view bool) NoteScreen {
+	a := NoteScreen{
+		api:  api,
+		view: view,
+	}
+
+	a.name = textinput.New()
+	a.name.Placeholder = "Имя заметки"
+	a.name.CharLimit = 64
+	a.name.Focus()
+	a.name.Prompt = "┃ "
+	a.name.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	a.password = textinput.New()
+	a.password.Placeholder = "Пароль"
+	a.password.CharLimit = 32
+	a.password.Prompt = "┃ "
+	a.password.EchoMode = textinput.EchoPassword
+	a.password.EchoCharacter = '•'
+
+	a.text = textarea.New()
+	a.text.Placeholder = "Текст заметки"
+	a.text.ShowLineNumbers = false
+	a.text.CharLimit = 0
+	a.text.MaxHeight = 0
+	a.text.SetWidth(50)
+	a.text.SetHeight(10)
+
+	return a
+}
+
+func (a NoteScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (a NoteScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		s := msg.String()
+		switch {
+		case s == "esc":
+
+			return a, tea.Quit
+
+		case s == "enter" && a.focus == noteFocusPassword:
+			a.errorMsg = ""
+			if a.view {
+				text, err := a.api.NoteShow(a.name.Value(), a.password.Value())
+				if err != nil {
+					a.errorMsg = err.Error()
+					return a, nil
+				}
+				a.text.SetValue(text)
+				return a, nil
+			}
+
+			if a.text.Value() == "" {
+				a.errorMsg = "пустая заметка"
+				return a, nil
+			}
+			if err := a.api.NoteSave(a.name.Value(), a.text.Value(), a.password.Value()); err != nil {
+				a.errorMsg = err.Error()
+				return a, nil
+			}
+			return a, func() tea.Msg {
+				return SwitchScreenMsg{ScreenName: "menu"}
+			}
+
+		// В поле текста Enter и стрелки редактируют заметку, поля
+		// переключаются только по Tab
+		case s == "tab" || s == "shift+tab" ||
+			a.focus != noteFocusText && (s == "enter" || s == "up" || s == "down"):
+			return a, a.move(s == "shift+tab" || s == "up")
+		}
+	}
+
+	// Обновляем текущее поле ввода
+	var cmd tea.Cmd
+	switch a.focus {
+	case noteFocusName:
+		a.name, cmd = a.name.Update(msg)
+	case noteFocusText:
+		a.text, cmd = a.text.Update(msg)
+	case noteFocusPassword:
+		a.password, cmd = a.password.Update(msg)
+	}
+
+	return a, cmd
+}
+
+// move переводит фокус на следующее или предыдущее поле.
+func (a *NoteScreen) move(back bool) tea.Cmd {
+	step := 1
+	if back {
+		step = -1
+	}
+	a.focus = (a.focus + step + 3) % 3
+	if a.view && a.focus == noteFocusText {
+		a.focus = (a.focus + step + 3) % 3
+	}
+
+	a.name.Blur()
+	a.text.Blur()
+	a.password.Blur()
+	switch a.focus {
+	case noteFocusName:
+		return a.name.Focus()
+	case noteFocusText:
+		return a.text.Focus()
+	default:
+		return a.password.Focus()
+	}
+}
+
+func (a NoteScreen) View() string {
+	title := "Новая заметка"
+	action := "Сохранить"
+	if a.view {
+		title = "Просмотр заметки"
+		action = "Показать"
+	}
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для полей ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	name := lipgloss.JoinVertical(lipgloss.Left, a.name.Placeholder+":", inputStyle.Render(a.name.View()))
+	password := lipgloss.JoinVertical(lipgloss.Left, a.password.Placeholder+":", inputStyle.Render(a.password.View()))
+
+	// Поля идут в порядке заполнения, расшифрованный текст — под паролем
+	form := lipgloss.JoinVertical(lipgloss.Left, name, a.text.View(), password)
+	if a.view {
+		form = lipgloss.JoinVertical(lipgloss.Left, name, password, "", a.text.View())
+	}
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submitBtn := " "
+	if a.focus == noteFocusPassword {
+		submitBtn = ">"
+	}
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(submitBtn + " " + action + " (Enter в поле пароля)")
+
+	// Возврат в меню
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("Tab: следующее поле • ESC: Отмена")
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+func (a *NoteScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}
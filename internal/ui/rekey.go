@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type RekeyScreen struct {
+	width    int
+	height   int
+	api      RekeyScreenAPI
+	inputs   []textinput.Model
+	focus    int
+	errorMsg string
+}
+
+func NewRekeyScreen(api RekeyScreenAPI) RekeyScreen {
+	a := RekeyScreen{
+		api:    api,
+		inputs: make([]textinput.Model, 3),
+	}
+
+	// Поле текущего пароля
+	a.inputs[0] = textinput.New()
+	a.inputs[0].Placeholder = "Текущий пароль"
+	a.inputs[0].CharLimit = 32
+	a.inputs[0].Focus()
+	a.inputs[0].Prompt = "┃ "
+	a.inputs[0].EchoMode = textinput.EchoPassword
+	a.inputs[0].EchoCharacter = '•'
+
+	// Поле нового пароля
+	a.inputs[1] = textinput.New()
+	a.inputs[1].Placeholder = "Новый пароль"
+	a.inputs[1].CharLimit = 32
+	a.inputs[1].Prompt = "┃ "
+	a.inputs[1].EchoMode = textinput.EchoPassword
+	a.inputs[1].EchoCharacter = '•'
+
+	// Повтор нового пароля
+	a.inputs[2] = textinput.New()
+	a.inputs[2].Placeholder = "Повторите новый пароль"
+	a.inputs[2].CharLimit = 32
+	a.inputs[2].Prompt = "┃ "
+	a.inputs[2].EchoMode = textinput.EchoPassword
+	a.inputs[2].EchoCharacter = '•'
+
+	return a
+}
+
+func (a RekeyScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (a RekeyScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			// Обработка навигации между полями
+			s := msg.String()
+
+			if s == "enter" && a.focus == len(a.inputs)-1 {
+				oldPassword := a.inputs[0].Value()
+				newPassword := a.inputs[1].Value()
+
+				if newPassword != a.inputs[2].Value() {
+					a.errorMsg = "пароли не совпадают"
+					return a, nil
+				}
+
+				if err := a.api.Rekey(oldPassword, newPassword); err != nil {
+					a.errorMsg = err.Error()
+					return a, nil
+				}
+
+				return a, func() tea.Msg {
+					return SwitchScreenMsg{ScreenName: "menu"}
+				}
+			}
+
+			// Циклическая навигация между полями
+			if s == "up" || s == "shift+tab" {
+				a.focus--
+			} else {
+				a.focus++
+			}
+
+			if a.focus >= len(a.inputs) {
+				a.focus = 0
+			} else if a.focus < 0 {
+				a.focus = len(a.inputs) - 1
+			}
+
+			// Устанавливаем фокус на текущее поле
+			cmds = make([]tea.Cmd, len(a.inputs))
+			for i := range a.inputs {
+				if i == a.focus {
+					cmds[i] = a.inputs[i].Focus()
+				} else {
+					a.inputs[i].Blur()
+				}
+			}
+			return a, tea.Batch(cmds...)
+
+		case "esc":
+
+			return a, tea.Quit
+		}
+	}
+
+	// Обновляем текущее поле ввода
+	var cmd tea.Cmd
+	a.inputs[a.focus], cmd = a.inputs[a.focus].Update(msg)
+	cmds = append(cmds, cmd)
+
+	return a, tea.Batch(cmds...)
+}
+
+func (a RekeyScreen) View() string {
+	title := "Смена пароля"
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для полей ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	// Собираем поля ввода с подписями
+	var fields []string
+	for i := range a.inputs {
+		fields = append(fields, a.inputs[i].Placeholder+":", inputStyle.Render(a.inputs[i].View()))
+	}
+	form := lipgloss.JoinVertical(lipgloss.Left, fields...)
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submitBtn := " "
+	if a.focus == len(a.inputs)-1 {
+		submitBtn = ">"
+	}
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(fmt.Sprintf("%s Сменить пароль (Enter)", submitBtn))
+
+	// Возврат в меню
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("ESC: Отмена")
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+func (a *RekeyScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}
@@ -0,0 +1,282 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// totpGenerator возвращает код TOTP на момент now и время до его смены.
+type totpGenerator = func(now time.Time) (string, time.Duration, error)
+
+// totpTickMsg ежесекундное обновление кода. Поколение отличает цепочку
+// тиков, запущенную последней, от прежних.
+type totpTickMsg struct {
+	gen int
+}
+
+// totpLoadedMsg результат расшифровки записи TOTP.
+type totpLoadedMsg struct {
+	gen      int
+	generate totpGenerator
+	err      error
+}
+
+// totpResumeMsg перезапускает обновление кода или расшифровку записи при
+// возврате на экран: пока открыт другой экран, сообщения до этого экрана
+// не доходят.
+type totpResumeMsg struct{}
+
+// TotpScreen экран одноразового кода TOTP. Запись расшифровывается один
+// раз в фоне, после чего код раз в секунду вычисляется без обращения
+// к хранилищу.
+type TotpScreen struct {
+	width    int
+	height   int
+	api      TotpScreenAPI
+	inputs   []textinput.Model
+	focus    int
+	generate totpGenerator
+	loading  bool
+	code     string
+	expires  time.Time
+	gen      int
+	errorMsg string
+}
+
+func NewTotpScreen(api TotpScreenAPI) TotpScreen {
+	a := TotpScreen{
+		api:    api,
+		inputs: make([]textinput.Model, 2),
+	}
+
+	a.inputs[0] = textinput.New()
+	a.inputs[0].Placeholder = "Имя записи"
+	a.inputs[0].CharLimit = 64
+	a.inputs[0].Focus()
+	a.inputs[0].Prompt = "┃ "
+	a.inputs[0].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	a.inputs[1] = textinput.New()
+	a.inputs[1].Placeholder = "Пароль"
+	a.inputs[1].CharLimit = 32
+	a.inputs[1].Prompt = "┃ "
+	a.inputs[1].EchoMode = textinput.EchoPassword
+	a.inputs[1].EchoCharacter = '•'
+
+	return a
+}
+
+func (a TotpScreen) Init() tea.Cmd {
+	if a.generate == nil && !a.loading {
+		return textinput.Blink
+	}
+	return tea.Batch(textinput.Blink, func() tea.Msg { return totpResumeMsg{} })
+}
+
+func (a TotpScreen) tick() tea.Cmd {
+	gen := a.gen
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return totpTickMsg{gen: gen}
+	})
+}
+
+// load расшифровывает запись вне цикла обработки сообщений: получение
+// ключа из пароля занимает заметное время.
+func (a TotpScreen) load() tea.Cmd {
+	gen, name, password := a.gen, a.inputs[0].Value(), a.inputs[1].Value()
+	return func() tea.Msg {
+		generate, err := a.api.TotpGenerator(name, password)
+		return totpLoadedMsg{gen: gen, generate: generate, err: err}
+	}
+}
+
+// refresh вычисляет текущий код и время его смены.
+func (a *TotpScreen) refresh() {
+	code, remaining, err := a.generate(time.Now())
+	if err != nil {
+		a.generate, a.code, a.errorMsg = nil, "", err.Error()
+		return
+	}
+	a.code, a.errorMsg = code, ""
+	a.expires = time.Now().Add(remaining)
+}
+
+// reset забывает расшифрованную запись и останавливает обновление кода.
+func (a *TotpScreen) reset() {
+	a.gen++
+	a.generate, a.loading, a.code = nil, false, ""
+}
+
+func (a TotpScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case totpResumeMsg:
+		a.gen++
+		// Результат расшифровки, начатой до ухода с экрана, получил другой
+		// экран, поэтому запись расшифровывается заново
+		if a.loading {
+			return a, a.load()
+		}
+		return a, a.tick()
+
+	case totpLoadedMsg:
+		if msg.gen != a.gen {
+			return a, nil
+		}
+		a.loading = false
+		if msg.err != nil {
+			a.errorMsg = msg.err.Error()
+			return a, nil
+		}
+		a.generate = msg.generate
+		a.refresh()
+		if a.generate == nil {
+			return a, nil
+		}
+		return a, a.tick()
+
+	case totpTickMsg:
+		if msg.gen != a.gen || a.generate == nil {
+			return a, nil
+		}
+		a.refresh()
+		if a.generate == nil {
+			return a, nil
+		}
+		return a, a.tick()
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "enter", "up", "down":
+			s := msg.String()
+
+			if s == "enter" && a.focus == len(a.inputs)-1 {
+				// Прежняя цепочка тиков останавливается
+				a.reset()
+				a.loading, a.errorMsg = true, ""
+				return a, a.load()
+			}
+
+			// Переключение между полями
+			if s == "up" || s == "shift+tab" {
+				a.focus--
+			} else {
+				a.focus++
+			}
+
+			if a.focus >= len(a.inputs) {
+				a.focus = 0
+			} else if a.focus < 0 {
+				a.focus = len(a.inputs) - 1
+			}
+			// Устанавливаем фокус на текущее поле
+			cmds = make([]tea.Cmd, len(a.inputs))
+			for i := range a.inputs {
+				if i == a.focus {
+					cmds[i] = a.inputs[i].Focus()
+				} else {
+					a.inputs[i].Blur()
+				}
+			}
+			return a, tea.Batch(cmds...)
+
+		case "esc":
+
+			return a, tea.Quit
+		}
+
+		// Код другой записи запрашивается заново
+		if a.focus == 0 {
+			a.reset()
+		}
+	}
+
+	// Обновляем текущее поле ввода
+	var cmd tea.Cmd
+	a.inputs[a.focus], cmd = a.inputs[a.focus].Update(msg)
+	cmds = append(cmds, cmd)
+
+	return a, tea.Batch(cmds...)
+}
+
+func (a TotpScreen) View() string {
+	title := "Одноразовый код"
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для полей ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	form := lipgloss.JoinVertical(
+		lipgloss.Left,
+		a.inputs[0].Placeholder+":",
+		inputStyle.Render(a.inputs[0].View()),
+		a.inputs[1].Placeholder+":",
+		inputStyle.Render(a.inputs[1].View()),
+	)
+
+	// Добавляем код и время до его смены
+	if a.code != "" {
+		remaining := int(time.Until(a.expires).Round(time.Second) / time.Second)
+		code := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("205")).
+			Bold(true).
+			Render(a.code)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", code, fmt.Sprintf("Осталось %d с", max(remaining, 0)))
+	}
+
+	if a.loading {
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", "Расшифровка записи…")
+	}
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submitBtn := " "
+	if a.focus == len(a.inputs)-1 {
+		submitBtn = ">"
+	}
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render(submitBtn + " Показать код (Enter)")
+
+	// Возврат в меню
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("ESC: Отмена")
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+func (a *TotpScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}
@@ -16,7 +16,15 @@ func NewUI(app KeeperApp) error {
 		"delete":     app,
 		"card":       app,
 		"deletecard": app,
+		"cred":       app,
+		"credupdate": app,
+		"credview":   app,
+		"note":       app,
+		"noteview":   app,
+		"totp":       app,
 		"sync":       app,
+		"rekey":      app,
+		"verify":     app,
 	}
 	fmt.Println("lol")
 
@@ -74,10 +82,42 @@ func initialModel(apis map[string]ScreenAPI) model {
 		m.screens["deletecard"] = NewDeletecardScreen(deletecardAPI)
 	}
 
+	if credAPI, ok := apis["cred"].(CredScreenAPI); ok {
+		m.screens["cred"] = NewCredScreen(credAPI, false)
+	}
+
+	if credAPI, ok := apis["credupdate"].(CredScreenAPI); ok {
+		m.screens["credupdate"] = NewCredScreen(credAPI, true)
+	}
+
+	if credViewAPI, ok := apis["credview"].(CredViewScreenAPI); ok {
+		m.screens["credview"] = NewCredViewScreen(credViewAPI)
+	}
+
+	if noteAPI, ok := apis["note"].(NoteScreenAPI); ok {
+		m.screens["note"] = NewNoteScreen(noteAPI, false)
+	}
+
+	if noteAPI, ok := apis["noteview"].(NoteScreenAPI); ok {
+		m.screens["noteview"] = NewNoteScreen(noteAPI, true)
+	}
+
+	if totpAPI, ok := apis["totp"].(TotpScreenAPI); ok {
+		m.screens["totp"] = NewTotpScreen(totpAPI)
+	}
+
 	if syncAPI, ok := apis["sync"].(SyncScreenAPI); ok {
 		m.screens["sync"] = NewSyncScreen(syncAPI)
 	}
 
+	if rekeyAPI, ok := apis["rekey"].(RekeyScreenAPI); ok {
+		m.screens["rekey"] = NewRekeyScreen(rekeyAPI)
+	}
+
+	if verifyAPI, ok := apis["verify"].(VerifyScreenAPI); ok {
+		m.screens["verify"] = NewVerifyScreen(verifyAPI)
+	}
+
 	return m
 }
 
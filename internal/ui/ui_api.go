@@ -1,16 +1,28 @@
 package ui
 
+import "time"
+
 type KeeperApp interface {
 	Register(Username string, Password string, Email string) error
 	Login(Username string, Password string) error
 	Encrypt(Password string, Input string, Output string) error
 	Decrypt(Password string, Input string, Output string) error
-	Upload(Output string, Category string) error
-	Download(Input string) error
+	Upload(Output string) error
+	Download(Password string, Input string) error
 	Delete(Input string) error
-	Card(Number string, Date string, CVV string, Password string) error
+	CheckCard(Number string, Date string, CVV string, PIN string) (string, bool, error)
+	Card(Number string, Date string, CVV string, Holder string, Bank string, PIN string, Notes string, Password string) error
 	Deletecard(Input string) error
+	CredAdd(Name string, URL string, Login string, Secret string, Notes string, Password string) error
+	CredGet(Name string, Password string) (string, error)
+	CredList(Password string) (string, error)
+	CredUpdate(Name string, URL *string, Login *string, Secret *string, Notes *string, Password string) error
+	NoteSave(Name string, Text string, Password string) error
+	NoteShow(Name string, Password string) (string, error)
+	TotpGenerator(Name string, Password string) (func(now time.Time) (string, time.Duration, error), error)
 	Sync(Username string) error
+	Rekey(OldPassword string, NewPassword string) error
+	Verify(Password string, JSON bool) (string, error)
 }
 
 type ScreenAPI interface {
@@ -37,13 +49,41 @@ type DeleteScreenAPI interface {
 }
 
 type CardScreenAPI interface {
-	Card(Number string, Date string, CVV string, Password string) error
+	CheckCard(Number string, Date string, CVV string, PIN string) (string, bool, error)
+	Card(Number string, Date string, CVV string, Holder string, Bank string, PIN string, Notes string, Password string) error
 }
 
 type DeletecardScreenAPI interface {
 	Delete(Input string) error
 }
 
+type CredScreenAPI interface {
+	CredAdd(Name string, URL string, Login string, Secret string, Notes string, Password string) error
+	CredUpdate(Name string, URL *string, Login *string, Secret *string, Notes *string, Password string) error
+}
+
+type CredViewScreenAPI interface {
+	CredGet(Name string, Password string) (string, error)
+	CredList(Password string) (string, error)
+}
+
+type NoteScreenAPI interface {
+	NoteSave(Name string, Text string, Password string) error
+	NoteShow(Name string, Password string) (string, error)
+}
+
+type TotpScreenAPI interface {
+	TotpGenerator(Name string, Password string) (func(now time.Time) (string, time.Duration, error), error)
+}
+
 type SyncScreenAPI interface {
 	Sync() error
 }
+
+type RekeyScreenAPI interface {
+	Rekey(OldPassword string, NewPassword string) error
+}
+
+type VerifyScreenAPI interface {
+	Verify(Password string, JSON bool) (string, error)
+}
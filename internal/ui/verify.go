@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type VerifyScreen struct {
+	width    int
+	height   int
+	api      VerifyScreenAPI
+	input    textinput.Model
+	report   string
+	errorMsg string
+}
+
+func NewVerifyScreen(api VerifyScreenAPI) VerifyScreen {
+	a := VerifyScreen{
+		api: api,
+	}
+
+	// Поле пароля
+	a.input = textinput.New()
+	a.input.Placeholder = "Пароль"
+	a.input.CharLimit = 32
+	a.input.Focus()
+	a.input.Prompt = "┃ "
+	a.input.EchoMode = textinput.EchoPassword
+	a.input.EchoCharacter = '•'
+
+	return a
+}
+
+func (a VerifyScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (a VerifyScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			// Отчёт показывается и тогда, когда найдены повреждённые файлы
+			a.report, a.errorMsg = "", ""
+			report, err := a.api.Verify(a.input.Value(), false)
+			a.report = report
+			if err != nil {
+				a.errorMsg = err.Error()
+			}
+			return a, nil
+
+		case "esc":
+
+			return a, tea.Quit
+		}
+	}
+
+	// Обновляем поле ввода
+	var cmd tea.Cmd
+	a.input, cmd = a.input.Update(msg)
+
+	return a, cmd
+}
+
+func (a VerifyScreen) View() string {
+	title := "Проверка файлов"
+	styledTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Align(lipgloss.Center).
+		Bold(true).
+		Render(title)
+
+	// Стили для поля ввода
+	inputStyle := lipgloss.NewStyle().
+		Width(30).
+		Padding(0, 1)
+
+	form := lipgloss.JoinVertical(lipgloss.Left, a.input.Placeholder+":", inputStyle.Render(a.input.View()))
+
+	// Добавляем отчёт
+	if a.report != "" {
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", a.report)
+	}
+
+	// Добавляем сообщение об ошибке
+	if a.errorMsg != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("Ошибка: " + a.errorMsg)
+		form = lipgloss.JoinVertical(lipgloss.Left, form, "", errorStyle)
+	}
+
+	// Кнопка отправки
+	submit := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("> Проверить (Enter)")
+
+	// Возврат в меню
+	back := lipgloss.NewStyle().
+		MarginTop(1).
+		Render("ESC: Отмена")
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			styledTitle,
+			"",
+			form,
+			"",
+			submit,
+			back,
+		),
+	)
+}
+
+func (a *VerifyScreen) SetSize(width, height int) {
+	a.width = width
+	a.height = height
+}